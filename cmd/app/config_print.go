@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/untibullet/subscription-service-em/internal/config"
+	"go.yaml.in/yaml/v3"
+)
+
+// configUsage - справка по команде config
+const configUsage = "usage: app config print|validate|schema"
+
+// configCommand - app config print|validate. print выводит итоговую
+// конфигурацию после config.yaml и переменных окружения со скрытыми секретами,
+// validate только проверяет ее: до вызова команды конфигурация уже загружена
+// и проверена. schema обрабатывается в run, без загрузки конфигурации
+func configCommand(_ context.Context, c *cli, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w; %s", errUsage, configUsage)
+	}
+	switch args[0] {
+	case "print":
+	case "validate":
+		fmt.Fprintln(c.out, "config is valid")
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown config command %q; %s", errUsage, args[0], configUsage)
+	}
+
+	enc := yaml.NewEncoder(c.out)
+	enc.SetIndent(2)
+	if err := enc.Encode(config.Redacted(c.cfg)); err != nil {
+		return fmt.Errorf("failed to print config: %w", err)
+	}
+	return enc.Close()
+}
+
+// printSchema выводит JSON Schema файла config.yaml
+func printSchema(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(config.Schema()); err != nil {
+		return fmt.Errorf("failed to print config schema: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/untibullet/subscription-service-em/internal/export"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+)
+
+// cliActor - инициатор изменений из служебных команд в журнале изменений
+const cliActor = "cli"
+
+// fixtures - тестовые подписки для seed. ID фиксированы, повторный seed их пропускает
+//
+//go:embed fixtures/subscriptions.ndjson
+var fixtures []byte
+
+// newFlagSet создает набор флагов команды, ошибки разбора выводятся в stderr
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// parseFlags разбирает флаги и отклоняет лишние позиционные аргументы
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", errUsage, fs.Arg(0))
+	}
+	return nil
+}
+
+// tenantContext возвращает контекст служебной команды в организации tenant
+func tenantContext(ctx context.Context, tenant string) context.Context {
+	return reqctx.WithActor(reqctx.WithTenant(ctx, tenant), cliActor)
+}
+
+// seedCommand - app seed: загружает встроенные тестовые подписки
+func seedCommand(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("seed")
+	tenant := fs.String("tenant", reqctx.DefaultTenant, "организация")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	r, err := export.NewReader(export.FormatNDJSON, bytes.NewReader(fixtures))
+	if err != nil {
+		return err
+	}
+	return loadSubscriptions(tenantContext(ctx, *tenant), c, r, false)
+}
+
+// exportCommand - app export: выгружает подписки в файл или stdout
+func exportCommand(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("export")
+	format := fs.String("format", string(export.FormatCSV), "формат: csv, ndjson, xlsx")
+	output := fs.String("output", "", "файл выгрузки (по умолчанию stdout)")
+	tenant := fs.String("tenant", reqctx.DefaultTenant, "организация")
+	userID := fs.String("user", "", "UUID пользователя")
+	serviceName := fs.String("service", "", "название сервиса")
+	includeDeleted := fs.Bool("include-deleted", false, "включать удаленные подписки")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	f, err := export.ParseFormat(*format)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	filter := models.SubscriptionFilter{IncludeDeleted: *includeDeleted}
+	if filter.UserID, err = parseUser(*userID); err != nil {
+		return err
+	}
+	if *serviceName != "" {
+		filter.ServiceName = serviceName
+	}
+
+	pool, err := connect(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	repo := repository.NewPostgresSubscriptionRepo(pool, nil, c.cfg.Outbox.Enabled)
+
+	out := c.out
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	buf := bufio.NewWriter(out)
+
+	w, err := export.NewWriter(f, buf)
+	if err != nil {
+		return err
+	}
+	rows := 0
+	err = repo.Stream(tenantContext(ctx, *tenant), filter, func(sub *models.Subscription) error {
+		rows++
+		return w.Write(sub)
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("export failed after %d rows: %w", rows, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d subscriptions\n", rows)
+	return nil
+}
+
+// importCommand - app import: загружает подписки из файла или stdin
+func importCommand(ctx context.Context, c *cli, args []string) error {
+	fs := newFlagSet("import")
+	format := fs.String("format", string(export.FormatCSV), "формат: csv, ndjson")
+	input := fs.String("input", "", "файл с подписками (по умолчанию stdin)")
+	tenant := fs.String("tenant", reqctx.DefaultTenant, "организация")
+	update := fs.Bool("update", false, "обновлять подписки с уже существующим id (по умолчанию пропускаются)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	f, err := export.ParseFormat(*format)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	var in io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return fmt.Errorf("failed to open input file: %w", err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	r, err := export.NewReader(f, bufio.NewReader(in))
+	if err != nil {
+		return err
+	}
+	return loadSubscriptions(tenantContext(ctx, *tenant), c, r, *update)
+}
+
+// loadSubscriptions записывает подписки из r через репозиторий, чтобы изменения
+// попали в журнал и outbox. Подписка без id создается с новым id, подписка
+// с существующим id пропускается или, при update, обновляется. Удаленные
+// подписки из выгрузки пропускаются, удаленные в БД не обновляются
+func loadSubscriptions(ctx context.Context, c *cli, r export.Reader, update bool) error {
+	pool, err := connect(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	repo := repository.NewPostgresSubscriptionRepo(pool, nil, c.cfg.Outbox.Enabled)
+
+	var created, updated, skipped int
+	report := func() {
+		fmt.Fprintf(c.out, "created %d, updated %d, skipped %d\n", created, updated, skipped)
+	}
+
+	for {
+		sub, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			report()
+			return err
+		}
+		if sub.DeletedAt != nil {
+			skipped++
+			continue
+		}
+
+		now := time.Now().UTC()
+		if sub.ID == uuid.Nil {
+			sub.ID = uuid.New()
+		} else {
+			existing, err := repo.GetByID(ctx, sub.ID, models.GetOptions{IncludeDeleted: true})
+			switch {
+			case err == nil && (!update || existing.DeletedAt != nil):
+				// удаленную подписку восстанавливают через API, а не импортом
+				skipped++
+				continue
+			case err == nil:
+				existing.ServiceName = sub.ServiceName
+				existing.Price = sub.Price
+				existing.StartDate = sub.StartDate
+				existing.EndDate = sub.EndDate
+				existing.UpdatedAt = now
+				if err := repo.Update(ctx, existing); err != nil {
+					report()
+					return fmt.Errorf("subscription %s: %w", sub.ID, err)
+				}
+				updated++
+				continue
+			case !errors.Is(err, repository.ErrNotFound):
+				report()
+				return fmt.Errorf("subscription %s: %w", sub.ID, err)
+			}
+		}
+
+		if sub.CreatedAt.IsZero() {
+			sub.CreatedAt = now
+		}
+		if sub.UpdatedAt.IsZero() {
+			sub.UpdatedAt = sub.CreatedAt
+		}
+		if err := repo.Create(ctx, sub); err != nil {
+			report()
+			return fmt.Errorf("subscription %s: %w", sub.ID, err)
+		}
+		created++
+	}
+
+	report()
+	return nil
+}
+
+// parseUser разбирает необязательный UUID пользователя из флага
+func parseUser(v string) (*uuid.UUID, error) {
+	if v == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user: %v", errUsage, err)
+	}
+	return &id, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/untibullet/subscription-service-em/internal/config"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"go.uber.org/zap"
+)
+
+// basePoolConfig возвращает настройки пула к БД dsn из секции database.
+// Нулевые значения оставляют умолчания pgxpool
+func basePoolConfig(db config.DatabaseConfig, dsn string) (*pgxpool.Config, error) {
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database config: %w", err)
+	}
+
+	if db.MaxConns > 0 {
+		poolCfg.MaxConns = db.MaxConns
+	}
+	if db.MinConns > 0 {
+		poolCfg.MinConns = db.MinConns
+	}
+	if db.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = db.MaxConnLifetime
+	}
+	if db.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = db.MaxConnIdleTime
+	}
+	if db.HealthCheckPeriod > 0 {
+		poolCfg.HealthCheckPeriod = db.HealthCheckPeriod
+	}
+
+	params := poolCfg.ConnConfig.RuntimeParams
+	if db.StatementTimeout > 0 {
+		params["statement_timeout"] = strconv.FormatInt(db.StatementTimeout.Milliseconds(), 10)
+	}
+	if db.ApplicationName != "" {
+		params["application_name"] = db.ApplicationName
+	}
+	return poolCfg, nil
+}
+
+// poolConfig возвращает настройки пула к БД dsn для работы с данными организаций
+func poolConfig(cfg *config.Config, dsn string) (*pgxpool.Config, error) {
+	poolCfg, err := basePoolConfig(cfg.Database, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Tenancy.RowLevelSecurity {
+		repository.EnableRowLevelSecurity(poolCfg)
+	} else {
+		repository.DisableRowLevelSecurity(poolCfg)
+	}
+	if cfg.Database.QueryComments {
+		repository.EnableQueryComments(poolCfg)
+	}
+	return poolCfg, nil
+}
+
+// openPool открывает пул и проверяет соединение. Пока Postgres недоступен
+// (например, контейнер БД еще стартует), попытки повторяются с
+// экспоненциальной задержкой, всего не больше db.ConnectAttempts
+func openPool(ctx context.Context, poolCfg *pgxpool.Config, db config.DatabaseConfig, log *zap.Logger) (*pgxpool.Pool, error) {
+	backoff := db.ConnectBackoff
+	for attempt := 1; ; attempt++ {
+		pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+		if err == nil {
+			if err = pool.Ping(ctx); err == nil {
+				return pool, nil
+			}
+			pool.Close()
+		}
+
+		if attempt >= db.ConnectAttempts {
+			return nil, fmt.Errorf("database is unavailable after %d attempts: %w", attempt, err)
+		}
+		log.Warn("database is unavailable, retrying", zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, db.ConnectMaxBackoff)
+	}
+}
+
+// openReplicas создает пулы реплик из database.replicas. Соединения
+// устанавливаются по мере надобности, поэтому недоступная при старте реплика
+// не мешает запуску, а ReplicaSet не использует ее до успешной проверки
+func openReplicas(ctx context.Context, cfg *config.Config, tracer pgx.QueryTracer) ([]*pgxpool.Pool, error) {
+	pools := make([]*pgxpool.Pool, 0, len(cfg.Database.Replicas))
+	for _, dsn := range cfg.Database.ReplicaDSNs() {
+		poolCfg, err := poolConfig(cfg, dsn)
+		if err != nil {
+			closePools(pools)
+			return nil, fmt.Errorf("invalid replica config: %w", err)
+		}
+		poolCfg.ConnConfig.Tracer = tracer
+		pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+		if err != nil {
+			closePools(pools)
+			return nil, fmt.Errorf("failed to create replica pool: %w", err)
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+func closePools(pools []*pgxpool.Pool) {
+	for _, p := range pools {
+		p.Close()
+	}
+}
+
+// connect открывает пул соединений для служебных команд
+func connect(ctx context.Context, c *cli) (*pgxpool.Pool, error) {
+	poolCfg, err := poolConfig(c.cfg, c.cfg.Database.GetDSN())
+	if err != nil {
+		return nil, err
+	}
+	return openPool(ctx, poolCfg, c.cfg.Database, c.log)
+}
@@ -2,17 +2,38 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	echoSwagger "github.com/swaggo/echo-swagger"
 	_ "github.com/untibullet/subscription-service-em/docs"
+	"github.com/untibullet/subscription-service-em/internal/auth"
+	"github.com/untibullet/subscription-service-em/internal/certs"
 	"github.com/untibullet/subscription-service-em/internal/config"
+	"github.com/untibullet/subscription-service-em/internal/health"
+	"github.com/untibullet/subscription-service-em/internal/logging"
+	"github.com/untibullet/subscription-service-em/internal/metrics"
+	"github.com/untibullet/subscription-service-em/internal/outbox"
+	"github.com/untibullet/subscription-service-em/internal/ratelimit"
 	"github.com/untibullet/subscription-service-em/internal/repository"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+	"github.com/untibullet/subscription-service-em/internal/retention"
 	"github.com/untibullet/subscription-service-em/internal/service"
+	"github.com/untibullet/subscription-service-em/internal/stream"
+	"github.com/untibullet/subscription-service-em/internal/tracing"
+	"github.com/untibullet/subscription-service-em/internal/webhook"
+	"github.com/untibullet/subscription-service-em/migrations"
 	"go.uber.org/zap"
 )
 
@@ -21,57 +42,489 @@ import (
 // @description API для управления подписками
 // @host localhost:9000
 // @BasePath /api/v1
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT в формате "Bearer <token>" или API-ключ в формате "ApiKey <ключ>" (если включена секция auth)
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// usage - справка по командам
+const usage = `usage: app [command] [flags]
+
+commands:
+  serve                      запустить HTTP-сервер (по умолчанию)
+  migrate up|down|status|redo
+                             применить, откатить миграции или показать их состояние
+  seed                       загрузить тестовые подписки
+  export                     выгрузить подписки в CSV, NDJSON или XLSX
+  import                     загрузить подписки из CSV или NDJSON
+  report cost                посчитать суммарную стоимость подписок за период
+  config print               вывести итоговую конфигурацию со скрытыми секретами
+  config validate            проверить конфигурацию
+  config schema              вывести JSON Schema файла config.yaml
+
+Флаги команды: app <command> -h
+`
+
+// cli - окружение подкоманд
+type cli struct {
+	cfg      *config.Config
+	log      *zap.Logger
+	logLevel zap.AtomicLevel
+	out      io.Writer // результат команды; логи пишутся отдельно
+}
+
+// commands - подкоманды бинарника
+var commands = map[string]func(ctx context.Context, c *cli, args []string) error{
+	"serve":   serve,
+	"migrate": migrateCommand,
+	"seed":    seedCommand,
+	"export":  exportCommand,
+	"import":  importCommand,
+	"report":  reportCommand,
+	"config":  configCommand,
+}
+
+// run выполняет команду и возвращает код завершения процесса
+func run(args []string) int {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	switch name {
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return 0
+	}
+	// схема нужна и тогда, когда config.yaml еще не проходит проверку
+	if name == "config" && len(args) == 1 && args[0] == "schema" {
+		if err := printSchema(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+		return 2
+	}
+
 	// Конфиг
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
-		os.Exit(1)
+		return 1
 	}
 
-	// Логгер
-	var logger *zap.Logger
-	if cfg.Env == "production" {
-		logger, _ = zap.NewProduction()
-	} else {
-		logger, _ = zap.NewDevelopment()
+	// Логгер. Остальные команды пишут результат в stdout, поэтому логи уходят в stderr
+	logCfg := logging.Config{
+		Level:              cfg.Logger.Level,
+		Format:             cfg.Logger.Format,
+		Development:        cfg.Env != "production",
+		OutputPaths:        cfg.Logger.OutputPaths,
+		ErrorOutputPaths:   cfg.Logger.ErrorOutputPaths,
+		SamplingInitial:    cfg.Logger.Sampling.Initial,
+		SamplingThereafter: cfg.Logger.Sampling.Thereafter,
+	}
+	ctx := context.Background()
+	if name != "serve" {
+		logCfg.OutputPaths = []string{"stderr"}
+		// serve обрабатывает сигналы сам
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+	}
+	logger, logLevel, err := logging.New(logCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %v\n", err)
+		return 1
 	}
 	defer logger.Sync()
 
+	err = cmd(ctx, &cli{cfg: cfg, log: logger, logLevel: logLevel, out: os.Stdout}, args)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 2
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// errUsage - неверные аргументы команды
+var errUsage = errors.New("invalid arguments")
+
+// serve запускает HTTP-сервер и фоновые задачи до SIGINT/SIGTERM
+func serve(_ context.Context, c *cli, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%w: serve takes no arguments", errUsage)
+	}
+	cfg, logger, logLevel := c.cfg, c.log, c.logLevel
+
+	// Трассировка
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		logger.Fatal("failed to set up tracing", zap.Error(err))
+	}
+
 	// БД
-	pool, err := pgxpool.New(context.Background(), cfg.Database.GetDSN())
+	poolCfg, err := poolConfig(cfg, cfg.Database.GetDSN())
+	if err != nil {
+		logger.Fatal("invalid database config", zap.Error(err))
+	}
+	var queryTracer pgx.QueryTracer
+	if cfg.Tracing.Exporter != "none" {
+		queryTracer = tracing.NewQueryTracer()
+	}
+	poolCfg.ConnConfig.Tracer = queryTracer
+	pool, err := openPool(context.Background(), poolCfg, cfg.Database, logger)
 	if err != nil {
 		logger.Fatal("failed to connect to database", zap.Error(err))
 	}
 	defer pool.Close()
+	logger.Info("database connected")
 
-	if err := pool.Ping(context.Background()); err != nil {
-		logger.Fatal("failed to ping database", zap.Error(err))
+	if cfg.Database.AutoMigrate {
+		if err := autoMigrate(context.Background(), cfg.Database, logger); err != nil {
+			logger.Fatal("auto migrate failed", zap.Error(err))
+		}
+	}
+
+	// Реплики для читающих запросов
+	var replicas *repository.ReplicaSet
+	if len(cfg.Database.Replicas) > 0 {
+		replicaPools, err := openReplicas(context.Background(), cfg, queryTracer)
+		if err != nil {
+			logger.Fatal("failed to open replicas", zap.Error(err))
+		}
+		defer closePools(replicaPools)
+		replicas = repository.NewReplicaSet(pool, replicaPools, cfg.Database.ReplicaMaxLag, cfg.Database.ReplicaCheckInterval, logger)
 	}
-	logger.Info("database connected")
 
 	// Repository
-	repo := repository.NewPostgresSubscriptionRepo(pool)
+	repo := repository.NewPostgresSubscriptionRepo(pool, replicas, cfg.Outbox.Enabled)
+	webhookRepo := repository.NewPostgresWebhookRepo(pool)
+	outboxRepo := repository.NewPostgresOutboxRepo(pool)
+	apiKeyRepo := repository.NewPostgresAPIKeyRepo(pool)
+	healthRepo := repository.NewPostgresHealthRepo(pool)
+
+	// Метрики. Репозиторий подписок оборачивается для измерения длительности запросов
+	var (
+		subs  repository.SubscriptionRepository = repo
+		audit repository.AuditRepository        = repo
+		m     *metrics.Metrics
+	)
+	if cfg.Metrics.Enabled {
+		m = metrics.New()
+		m.MustRegister(metrics.NewPoolCollector(pool))
+		subs = metrics.NewSubscriptionRepo(repo, m)
+		audit = metrics.NewAuditRepo(repo, m)
+	}
+
+	// Проверки готовности
+	schemaVersion, err := migrations.Latest()
+	if err != nil {
+		logger.Fatal("failed to read migrations", zap.Error(err))
+	}
+	checker := health.NewChecker(cfg.Health.CheckTimeout)
+	checker.Add("database", health.Database(healthRepo))
+	checker.Add("migrations", health.Migrations(healthRepo, schemaVersion))
+
+	// Фоновые задачи. Останавливаются после HTTP-сервера, до закрытия пула.
+	// Работают с данными всех организаций
+	ctx, stopWorkers := context.WithCancel(reqctx.WithSystem(context.Background()))
+	defer stopWorkers()
+	var workers sync.WaitGroup
+
+	if replicas != nil {
+		workers.Go(func() { replicas.Run(ctx) })
+	}
+
+	if cfg.Retention.Enabled {
+		purger := retention.NewPurger(subs, cfg.Retention.Period, cfg.Retention.Interval, logger)
+		workers.Go(func() { purger.Run(ctx) })
+		checker.Add("retention_purger", purger.Check)
+	}
+
+	if cfg.Outbox.Enabled {
+		publishers := outbox.MultiPublisher{}
+		for _, name := range cfg.Outbox.Publishers {
+			switch name {
+			case "log":
+				publishers = append(publishers, outbox.NewLogPublisher(logger))
+			case "memory":
+				publishers = append(publishers, outbox.NewMemoryPublisher())
+			case "nats":
+				p, err := outbox.NewNATSPublisher(cfg.Outbox.NATS.URL, cfg.Outbox.NATS.SubjectPrefix)
+				if err != nil {
+					logger.Fatal("failed to connect to nats", zap.Error(err))
+				}
+				defer p.Close()
+				publishers = append(publishers, p)
+			case "kafka":
+				p := outbox.NewKafkaPublisher(cfg.Outbox.Kafka.Brokers, cfg.Outbox.Kafka.Topic)
+				defer p.Close()
+				publishers = append(publishers, p)
+			}
+		}
+
+		if cfg.Webhooks.Enabled {
+			publishers = append(publishers, webhook.NewPublisher(webhookRepo))
+
+			dispatcher := webhook.NewDispatcher(webhookRepo, webhook.DispatcherConfig{
+				PollInterval:   cfg.Webhooks.PollInterval,
+				BatchSize:      cfg.Webhooks.BatchSize,
+				Concurrency:    cfg.Webhooks.Concurrency,
+				RequestTimeout: cfg.Webhooks.RequestTimeout,
+				MaxAttempts:    cfg.Webhooks.MaxAttempts,
+				InitialBackoff: cfg.Webhooks.InitialBackoff,
+				MaxBackoff:     cfg.Webhooks.MaxBackoff,
+			}, logger)
+			workers.Go(func() { dispatcher.Run(ctx) })
+			checker.Add("webhook_dispatcher", dispatcher.Check)
+		}
+
+		relay := outbox.NewRelay(outboxRepo, publishers, outbox.RelayConfig{
+			PollInterval:    cfg.Outbox.PollInterval,
+			BatchSize:       cfg.Outbox.BatchSize,
+			Lease:           cfg.Outbox.Lease,
+			InitialBackoff:  cfg.Outbox.InitialBackoff,
+			MaxBackoff:      cfg.Outbox.MaxBackoff,
+			Retention:       cfg.Outbox.Retention,
+			CleanupInterval: cfg.Outbox.CleanupInterval,
+		}, logger)
+		workers.Go(func() { relay.Run(ctx) })
+		checker.Add("outbox_relay", relay.Check)
+
+		renewals := outbox.NewRenewalScheduler(subs, outboxRepo, cfg.Outbox.RenewalLead, cfg.Outbox.RenewalInterval, logger)
+		workers.Go(func() { renewals.Run(ctx) })
+		checker.Add("renewal_scheduler", renewals.Check)
+	}
+
+	// потоки событий не завершаются сами, поэтому брокер останавливается в начале
+	// остановки сервера и закрывает их, не дожидаясь shutdown_timeout
+	streamCtx, stopStreams := context.WithCancel(ctx)
+	defer stopStreams()
+
+	var eventStream *service.EventStreamHTTPService
+	if cfg.Events.Enabled {
+		broker := stream.NewBroker(repository.NewPostgresEventListener(pool), cfg.Events.ReplaySize, cfg.Events.ClientBuffer, logger)
+		workers.Go(func() { broker.Run(streamCtx) })
+		checker.Add("event_stream", broker.Check)
+		eventStream = service.NewEventStreamHTTPService(broker, cfg.Events.Heartbeat, logger)
+	}
+
+	if m != nil {
+		business := metrics.NewBusinessCollector(repo, m, cfg.Metrics.BusinessInterval, logger)
+		workers.Go(func() { business.Run(ctx) })
+	}
+
+	// Лимитер создается и при выключенных лимитах, чтобы их можно было включить
+	// без перезапуска
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Store == "postgres" {
+		store = ratelimit.NewPostgresStore(repository.NewPostgresRateLimitRepo(pool))
+	}
+	limiter := ratelimit.NewLimiter(store, rateLimits(cfg.RateLimit), cfg.RateLimit.CleanupInterval, logger)
+	workers.Go(func() { limiter.Run(ctx) })
+
+	// Изменения config.yaml применяются без перезапуска
+	origins := service.NewAllowedOrigins(cfg.CORS.AllowOrigins)
+	reloads := &reloader{started: cfg, current: cfg, logLevel: logLevel, limiter: limiter, origins: origins, log: logger}
+	if err := config.Watch(reloads.reload); err != nil {
+		logger.Warn("config hot reload is disabled", zap.Error(err))
+	}
 
 	// Сервис
-	httpService := service.NewHTTPService(repo, logger)
+	httpService := service.NewHTTPService(subs, audit, logger)
+	webhookService := service.NewWebhookHTTPService(webhookRepo, logger)
+	apiKeyService := service.NewAPIKeyHTTPService(apiKeyRepo, logger)
+	logLevelService := service.NewLogLevelHTTPService(logLevel, logger)
+	healthService := service.NewHealthHTTPService(checker, logger)
 
-	// Echo
+	// Echo. Документация и проверки оркестратора доступны без аутентификации и лимитов
+	publicPaths := []string{"/swagger/", "/healthz", "/readyz"}
 	e := echo.New()
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
-	e.Use(middleware.CORS())
+	e.Server.ReadTimeout = cfg.Server.ReadTimeout
+	e.Server.ReadHeaderTimeout = cfg.Server.ReadHeaderTimeout
+	e.Server.WriteTimeout = cfg.Server.WriteTimeout
+	e.Server.IdleTimeout = cfg.Server.IdleTimeout
+	e.Server.MaxHeaderBytes = cfg.Server.MaxHeaderBytes
+	e.Server.RegisterOnShutdown(stopStreams)
+	if t := cfg.Server.TLS; t.CertFile != "" {
+		reloader, err := certs.NewReloader(certs.Files{CertFile: t.CertFile, KeyFile: t.KeyFile, ClientCAFile: t.ClientCAFile}, logger)
+		if err != nil {
+			logger.Fatal("failed to load tls certificates", zap.Error(err))
+		}
+		workers.Go(func() { reloader.Run(ctx) })
+		e.Server.TLSConfig = reloader.TLSConfig()
+	}
+	e.HTTPErrorHandler = service.HTTPErrorHandler(logger)
+	e.Use(service.RequestLogger(logger, "/healthz", "/readyz", cfg.Metrics.Path))
+	e.Use(service.RequestContext())
+	if m != nil {
+		// до Recover, чтобы запросы с паникой учитывались со статусом 500
+		e.Use(m.Middleware())
+	}
+	e.Use(tracing.Middleware("/healthz", "/readyz", cfg.Metrics.Path))
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
+		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
+			logger.Error("panic recovered", zap.String("uri", c.Request().RequestURI), zap.Error(err), zap.ByteString("stack", stack))
+			return err
+		},
+	}))
+	e.Use(service.CORS(origins))
+	// IP клиента берется из X-Forwarded-For только от прокси из частных сетей,
+	// иначе клиент мог бы обойти лимит, подставляя заголовок. Лимит на IP
+	// действует до аутентификации, лимит клиента - после нее
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	e.Use(service.IPRateLimit(limiter, logger, publicPaths...))
+	if cfg.Server.TLS.ClientCAFile != "" {
+		e.Use(service.ClientCertificate(logger, publicPaths...))
+	}
+	if cfg.Auth.Enabled {
+		jwtKeys, err := authKeys(cfg.Auth, logger)
+		if err != nil {
+			logger.Fatal("failed to load auth keys", zap.Error(err))
+		}
+		authn := auth.NewAuthenticator(jwtKeys, auth.Config{
+			Issuer:     cfg.Auth.Issuer,
+			Audience:   cfg.Auth.Audience,
+			Algorithms: cfg.Auth.Algorithms,
+			ClockSkew:  cfg.Auth.ClockSkew,
+
+			UserIDClaim: cfg.Auth.UserIDClaim,
+			TenantClaim: cfg.Auth.TenantClaim,
+			RolesClaim:  cfg.Auth.RolesClaim,
+			AdminRole:   cfg.Auth.AdminRole,
+		})
+		var apiKeys *auth.APIKeyVerifier
+		if cfg.Auth.APIKeys {
+			apiKeys = auth.NewAPIKeyVerifier(apiKeyRepo, logger)
+		}
+		e.Use(service.Authentication(authn, apiKeys, logger, publicPaths...))
+	}
+	if cfg.Auth.AnonymousAdmin {
+		logger.Warn("authentication is disabled and every client is an admin (auth.anonymous_admin); never use this outside development")
+	}
+	e.Use(service.Authorization(cfg.Auth.AnonymousAdmin, logger))
+	e.Use(service.Tenancy(cfg.Tenancy.Header, logger))
+	e.Use(service.RateLimit(limiter, logger, publicPaths...))
 
 	// Ручки
+	healthService.RegisterRoutes(e)
 	httpService.RegisterRoutes(e)
+	webhookService.RegisterRoutes(e)
+	logLevelService.RegisterRoutes(e)
+	if eventStream != nil {
+		eventStream.RegisterRoutes(e)
+	}
+	if cfg.Auth.Enabled && cfg.Auth.APIKeys {
+		apiKeyService.RegisterRoutes(e)
+	}
+
+	// Метрики содержат показатели всех организаций: без аутентификации они
+	// доступны только на отдельном адресе, на основном порту - администраторам
+	var metricsServer *http.Server
+	if m != nil && cfg.Metrics.Listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle(cfg.Metrics.Path, m.Handler())
+		metricsServer = &http.Server{
+			Addr:              cfg.Metrics.Listen,
+			Handler:           mux,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		}
+	} else if m != nil {
+		e.GET(cfg.Metrics.Path, echo.WrapHandler(m.Handler()), service.RequireAdmin)
+	}
 
 	// Swagger UI
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
 	// Старт
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
 	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
-	logger.Info("starting server", zap.String("addr", addr))
-	if err := e.Start(addr); err != nil {
-		logger.Fatal("server error", zap.Error(err))
+	serverErr := make(chan error, 2)
+	go func() {
+		logger.Info("starting server", zap.String("addr", addr), zap.Bool("tls", e.Server.TLSConfig != nil))
+		e.Server.Addr = addr
+		// с TLSConfig echo слушает HTTPS, HTTP/2 согласуется через ALPN
+		serverErr <- e.StartServer(e.Server)
+	}()
+	if metricsServer != nil {
+		go func() {
+			logger.Info("starting metrics server", zap.String("addr", metricsServer.Addr))
+			serverErr <- metricsServer.ListenAndServe()
+		}()
+	}
+
+	select {
+	case <-signals.Done():
+		// повторный сигнал завершает процесс сразу
+		stopSignals()
+		logger.Info("shutting down", zap.Duration("drain_delay", cfg.Health.DrainDelay), zap.Duration("timeout", cfg.Server.ShutdownTimeout))
+		// балансировщик снимает трафик по /readyz, пока сервер еще принимает запросы
+		checker.Drain()
+		time.Sleep(cfg.Health.DrainDelay)
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("server error", zap.Error(err))
+		}
+	}
+
+	// Остановка: сервер перестает принимать соединения и дожидается текущих
+	// запросов, затем останавливаются фоновые задачи, последним закрывается пул
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		logger.Error("server shutdown failed", zap.Error(err))
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("metrics server shutdown failed", zap.Error(err))
+		}
+	}
+
+	stopWorkers()
+	workers.Wait()
+
+	// отправляем накопленные span, пока есть время до принудительной остановки
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("tracing shutdown failed", zap.Error(err))
+	}
+	logger.Info("server stopped")
+	return nil
+}
+
+// authKeys собирает ключи проверки JWT из JWKS и общего секрета
+func authKeys(cfg config.AuthConfig, log *zap.Logger) (auth.KeySource, error) {
+	var sources auth.KeySources
+	switch {
+	case cfg.JWKSFile != "":
+		keys, err := auth.LoadJWKSFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, keys)
+	case cfg.JWKSURL != "":
+		sources = append(sources, auth.NewRemoteJWKS(cfg.JWKSURL, cfg.JWKSRefresh, log))
+	}
+	if cfg.HMACSecret != "" {
+		sources = append(sources, auth.StaticKeys{{Alg: "HS256", Key: []byte(cfg.HMACSecret)}})
 	}
+	return sources, nil
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/untibullet/subscription-service-em/internal/config"
+	"github.com/untibullet/subscription-service-em/migrations"
+	"go.uber.org/zap"
+)
+
+// migrateUsage - справка по команде migrate
+const migrateUsage = "usage: app migrate up|down|status|redo"
+
+// migrateCommand - app migrate up|down|status|redo
+func migrateCommand(ctx context.Context, c *cli, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w; %s", errUsage, migrateUsage)
+	}
+
+	pool, err := openMigrationPool(ctx, c.cfg.Database, c.log)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	return runMigrate(ctx, stdlib.OpenDBFromPool(pool), args, c.out)
+}
+
+// openMigrationPool открывает пул для миграций: без RLS и комментариев,
+// потому что миграции меняют схему, а не данные организаций
+func openMigrationPool(ctx context.Context, db config.DatabaseConfig, log *zap.Logger) (*pgxpool.Pool, error) {
+	poolCfg, err := basePoolConfig(db, db.GetDSN())
+	if err != nil {
+		return nil, err
+	}
+	// миграции, например построение индекса, могут идти дольше statement_timeout
+	delete(poolCfg.ConnConfig.RuntimeParams, "statement_timeout")
+	return openPool(ctx, poolCfg, db, log)
+}
+
+// runMigrate выполняет команду migrate и выводит результат в out:
+// up - применить все новые миграции, down - откатить последнюю,
+// redo - откатить и заново применить последнюю, status - состояние миграций
+func runMigrate(ctx context.Context, db *sql.DB, args []string, out io.Writer) error {
+	provider, err := migrations.NewProvider(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		results, err := up(ctx, provider)
+		printResults(out, results)
+		if err == nil && len(results) == 0 {
+			fmt.Fprintln(out, "schema is up to date")
+		}
+		return err
+	case "down":
+		result, err := provider.Down(ctx)
+		if err != nil {
+			return err
+		}
+		printResults(out, []*goose.MigrationResult{result})
+		return nil
+	case "redo":
+		result, err := provider.Down(ctx)
+		if err != nil {
+			return err
+		}
+		printResults(out, []*goose.MigrationResult{result})
+		result, err = provider.ApplyVersion(ctx, result.Source.Version, true)
+		if err != nil {
+			return err
+		}
+		printResults(out, []*goose.MigrationResult{result})
+		return nil
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tFILE")
+		for _, s := range statuses {
+			applied := "-"
+			if !s.AppliedAt.IsZero() {
+				applied = s.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, applied, s.Source.Path)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("%w: unknown migrate command %q; %s", errUsage, args[0], migrateUsage)
+	}
+}
+
+// up применяет новые миграции и возвращает примененные, в том числе при ошибке
+// одной из следующих
+func up(ctx context.Context, provider *goose.Provider) ([]*goose.MigrationResult, error) {
+	results, err := provider.Up(ctx)
+	var partial *goose.PartialError
+	if errors.As(err, &partial) {
+		results = partial.Applied
+	}
+	return results, err
+}
+
+func printResults(out io.Writer, results []*goose.MigrationResult) {
+	for _, r := range results {
+		fmt.Fprintln(out, r)
+	}
+}
+
+// autoMigrate применяет новые миграции при старте сервиса через отдельный
+// пул, как команда migrate
+func autoMigrate(ctx context.Context, db config.DatabaseConfig, log *zap.Logger) error {
+	pool, err := openMigrationPool(ctx, db, log)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	provider, err := migrations.NewProvider(stdlib.OpenDBFromPool(pool))
+	if err != nil {
+		return err
+	}
+
+	results, err := up(ctx, provider)
+	for _, r := range results {
+		log.Info("migration applied", zap.Int64("version", r.Source.Version), zap.String("file", r.Source.Path), zap.Duration("duration", r.Duration))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	if len(results) == 0 {
+		log.Info("database schema is up to date")
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+
+	"github.com/untibullet/subscription-service-em/internal/config"
+	"github.com/untibullet/subscription-service-em/internal/ratelimit"
+	"github.com/untibullet/subscription-service-em/internal/service"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// rateLimits возвращает лимиты групп маршрутов или nil, если ограничение выключено
+func rateLimits(cfg config.RateLimitConfig) map[ratelimit.Group]ratelimit.Limit {
+	if !cfg.Enabled {
+		return nil
+	}
+	return map[ratelimit.Group]ratelimit.Limit{
+		ratelimit.GroupRead:    ratelimit.Limit(cfg.Read),
+		ratelimit.GroupWrite:   ratelimit.Limit(cfg.Write),
+		ratelimit.GroupReports: ratelimit.Limit(cfg.Reports),
+		ratelimit.GroupIP:      ratelimit.Limit(cfg.IP),
+	}
+}
+
+// reloader применяет изменения config.yaml без перезапуска сервиса: уровень
+// логирования, лимиты запросов и источники CORS. Об остальных изменениях
+// сообщает как об ожидающих перезапуска
+type reloader struct {
+	started  *config.Config // настройки, с которыми запущен сервис
+	current  *config.Config // последние примененные настройки
+	logLevel zap.AtomicLevel
+	limiter  *ratelimit.Limiter
+	origins  *service.AllowedOrigins
+	log      *zap.Logger
+
+	pending []config.Change // о каких изменениях уже сообщено
+}
+
+// reload реализует обработчик config.Watch. Конфигурация с ошибкой не
+// применяется, сервис продолжает работать с текущими настройками
+func (r *reloader) reload(cfg *config.Config, err error) {
+	if err != nil {
+		r.log.Error("config reload failed, keeping current settings", zap.Error(err))
+		return
+	}
+
+	changes, _ := config.Diff(r.current, cfg)
+	_, pending := config.Diff(r.started, cfg)
+
+	// уровень, заданный через /admin/log-level, меняется только при изменении в файле
+	if cfg.Logger.Level != r.current.Logger.Level {
+		level, _ := zapcore.ParseLevel(cfg.Logger.Level)
+		r.logLevel.SetLevel(level)
+	}
+	r.limiter.SetLimits(rateLimits(cfg.RateLimit))
+	r.origins.Set(cfg.CORS.AllowOrigins)
+	r.current = cfg
+
+	if len(changes) > 0 {
+		r.log.Info("config reloaded", zap.Any("changes", changes))
+	}
+	if len(pending) > 0 && !reflect.DeepEqual(pending, r.pending) {
+		r.log.Warn("config changes require restart", zap.Any("pending", pending))
+	}
+	r.pending = pending
+}
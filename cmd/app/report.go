@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/untibullet/subscription-service-em/internal/export"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+)
+
+// reportUsage - справка по команде report
+const reportUsage = "usage: app report cost --from MM-YYYY --to MM-YYYY [--user UUID] [--service NAME]"
+
+// reportCommand - app report cost: суммарная стоимость подписок за период,
+// как GET /api/v1/subscriptions/cost
+func reportCommand(ctx context.Context, c *cli, args []string) error {
+	if len(args) == 0 || args[0] != "cost" {
+		return fmt.Errorf("%w; %s", errUsage, reportUsage)
+	}
+
+	fs := newFlagSet("report cost")
+	from := fs.String("from", "", "начало периода, MM-YYYY")
+	to := fs.String("to", "", "конец периода включительно, MM-YYYY")
+	tenant := fs.String("tenant", reqctx.DefaultTenant, "организация")
+	userID := fs.String("user", "", "UUID пользователя")
+	serviceName := fs.String("service", "", "название сервиса")
+	includeDeleted := fs.Bool("include-deleted", false, "учитывать удаленные подписки")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	if *from == "" || *to == "" {
+		return fmt.Errorf("%w: --from and --to are required", errUsage)
+	}
+	start, err := time.Parse(export.MonthLayout, *from)
+	if err != nil {
+		return fmt.Errorf("%w: invalid --from: %v", errUsage, err)
+	}
+	end, err := time.Parse(export.MonthLayout, *to)
+	if err != nil {
+		return fmt.Errorf("%w: invalid --to: %v", errUsage, err)
+	}
+
+	filter := models.CostFilter{StartPeriod: start, EndPeriod: end, IncludeDeleted: *includeDeleted}
+	if filter.UserID, err = parseUser(*userID); err != nil {
+		return err
+	}
+	if *serviceName != "" {
+		filter.ServiceName = serviceName
+	}
+
+	pool, err := connect(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	total, err := repository.NewPostgresSubscriptionRepo(pool, nil, c.cfg.Outbox.Enabled).CalculateCost(reqctx.WithTenant(ctx, *tenant), filter)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "%d\n", total)
+	return nil
+}
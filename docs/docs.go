@@ -15,71 +15,49 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/subscriptions": {
+        "/api/v1/admin/log-level": {
             "get": {
-                "description": "Возвращает список подписок с фильтрацией и пагинацией",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
-                ],
-                "summary": "Список подписок",
-                "operationId": "list-subscriptions",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "UUID пользователя",
-                        "name": "user_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Название сервиса",
-                        "name": "service_name",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 50,
-                        "description": "Количество элементов (макс. 500)",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 0,
-                        "description": "Смещение",
-                        "name": "offset",
-                        "in": "query"
-                    }
+                    "admin"
                 ],
+                "summary": "Текущий уровень логирования",
+                "operationId": "get-log-level",
                 "responses": {
                     "200": {
-                        "description": "Список подписок",
+                        "description": "Уровень логирования",
                         "schema": {
-                            "$ref": "#/definitions/internal_service.listResp"
+                            "$ref": "#/definitions/internal_service.logLevel"
                         }
                     },
-                    "400": {
-                        "description": "Неверный запрос",
+                    "403": {
+                        "description": "Требуется роль администратора",
                         "schema": {
                             "$ref": "#/definitions/echo.Map"
                         }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера",
+                    "429": {
+                        "description": "Превышен лимит запросов",
                         "schema": {
                             "$ref": "#/definitions/echo.Map"
                         }
                     }
                 }
             },
-            "post": {
-                "description": "Создаёт новую подписку на сервис",
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Меняет уровень логирования экземпляра сервиса до перезапуска. Уровень из конфигурации восстанавливается при следующем запуске",
                 "consumes": [
                     "application/json"
                 ],
@@ -87,26 +65,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "admin"
                 ],
-                "summary": "Создать новую подписку",
-                "operationId": "create-subscription",
+                "summary": "Изменить уровень логирования",
+                "operationId": "set-log-level",
                 "parameters": [
                     {
-                        "description": "Данные подписки",
+                        "description": "Новый уровень",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_service.createReq"
+                            "$ref": "#/definitions/internal_service.logLevel"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Созданная подписка",
+                    "200": {
+                        "description": "Установленный уровень",
                         "schema": {
-                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Subscription"
+                            "$ref": "#/definitions/internal_service.logLevel"
                         }
                     },
                     "400": {
@@ -115,8 +93,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/echo.Map"
                         }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера",
+                    "403": {
+                        "description": "Требуется роль администратора",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
                         "schema": {
                             "$ref": "#/definitions/echo.Map"
                         }
@@ -124,9 +108,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/subscriptions/cost": {
+        "/api/v1/api-keys": {
             "get": {
-                "description": "Возвращает суммарную стоимость подписок пользователя или сервиса за указанный период",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает все ключи, включая отозванные. Сами ключи не возвращаются",
                 "consumes": [
                     "application/json"
                 ],
@@ -134,47 +123,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
-                ],
-                "summary": "Рассчитать стоимость подписок за период",
-                "operationId": "calculate-cost",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Начало периода (формат MM-YYYY)",
-                        "name": "start_period",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Конец периода (формат MM-YYYY)",
-                        "name": "end_period",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "UUID пользователя",
-                        "name": "user_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Название сервиса",
-                        "name": "service_name",
-                        "in": "query"
-                    }
+                    "api-keys"
                 ],
+                "summary": "Список API-ключей",
+                "operationId": "list-api-keys",
                 "responses": {
                     "200": {
-                        "description": "Суммарная стоимость",
+                        "description": "Список ключей",
                         "schema": {
-                            "$ref": "#/definitions/internal_service.costResp"
+                            "$ref": "#/definitions/internal_service.apiKeyListResp"
                         }
                     },
-                    "400": {
-                        "description": "Неверный запрос",
+                    "403": {
+                        "description": "Требуется роль администратора",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
                         "schema": {
                             "$ref": "#/definitions/echo.Map"
                         }
@@ -186,11 +153,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/subscriptions/{id}": {
-            "get": {
-                "description": "Возвращает информацию о подписке по её уникальному идентификатору",
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создаёт ключ для сервисного клиента. Ключ возвращается только в этом ответе, сервис хранит лишь его хеш. Ключ передаётся в заголовке Authorization: ApiKey \u003cключ\u003e",
                 "consumes": [
                     "application/json"
                 ],
@@ -198,34 +168,48 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "api-keys"
                 ],
-                "summary": "Получить подписку по ID",
-                "operationId": "get-subscription-by-id",
+                "summary": "Выпустить API-ключ",
+                "operationId": "create-api-key",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "UUID идентификатор подписки",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Данные ключа",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_service.createAPIKeyReq"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Информация о подписке",
+                    "201": {
+                        "description": "Выпущенный ключ",
                         "schema": {
-                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Subscription"
+                            "$ref": "#/definitions/internal_service.apiKeyResp"
                         }
                     },
                     "400": {
-                        "description": "Неверный формат ID",
+                        "description": "Неверный запрос",
                         "schema": {
                             "$ref": "#/definitions/echo.Map"
                         }
                     },
-                    "404": {
-                        "description": "Подписка не найдена",
+                    "403": {
+                        "description": "Требуется роль администратора",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "409": {
+                        "description": "Ключ с таким именем уже существует",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
                         "schema": {
                             "$ref": "#/definitions/echo.Map"
                         }
@@ -237,9 +221,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Обновляет существующую подписку по её ID",
+            }
+        },
+        "/api/v1/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отзывает ключ. Запись о ключе сохраняется в списке с отметкой revoked_at",
                 "consumes": [
                     "application/json"
                 ],
@@ -247,43 +238,43 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "api-keys"
                 ],
-                "summary": "Обновить подписку",
-                "operationId": "update-subscription",
+                "summary": "Отозвать API-ключ",
+                "operationId": "revoke-api-key",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "UUID идентификатор подписки",
+                        "description": "UUID ключа",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Данные для обновления",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/internal_service.updateReq"
-                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Обновлённая подписка",
+                    "204": {
+                        "description": "Ключ отозван"
+                    },
+                    "400": {
+                        "description": "Неверный формат ID",
                         "schema": {
-                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Subscription"
+                            "$ref": "#/definitions/echo.Map"
                         }
                     },
-                    "400": {
-                        "description": "Неверный запрос",
+                    "403": {
+                        "description": "Требуется роль администратора",
                         "schema": {
                             "$ref": "#/definitions/echo.Map"
                         }
                     },
                     "404": {
-                        "description": "Подписка не найдена",
+                        "description": "Действующий ключ не найден",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
                         "schema": {
                             "$ref": "#/definitions/echo.Map"
                         }
@@ -295,9 +286,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Удаляет подписку по её ID",
+            }
+        },
+        "/api/v1/api-keys/{id}/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Выпускает новый секрет действующего ключа с теми же правами и сроком действия. Прежний секрет перестаёт действовать сразу",
                 "consumes": [
                     "application/json"
                 ],
@@ -305,22 +303,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "api-keys"
                 ],
-                "summary": "Удалить подписку",
-                "operationId": "delete-subscription",
+                "summary": "Ротировать API-ключ",
+                "operationId": "rotate-api-key",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "UUID идентификатор подписки",
+                        "description": "UUID ключа",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Подписка удалена"
+                    "200": {
+                        "description": "Ключ с новым секретом",
+                        "schema": {
+                            "$ref": "#/definitions/internal_service.apiKeyResp"
+                        }
                     },
                     "400": {
                         "description": "Неверный формат ID",
@@ -328,8 +329,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/echo.Map"
                         }
                     },
+                    "403": {
+                        "description": "Требуется роль администратора",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
                     "404": {
-                        "description": "Подписка не найдена",
+                        "description": "Действующий ключ не найден",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
                         "schema": {
                             "$ref": "#/definitions/echo.Map"
                         }
@@ -342,53 +355,1887 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "echo.Map": {
-            "type": "object",
-            "additionalProperties": true
-        },
-        "github_com_untibullet_subscription-service-em_internal_models.Subscription": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "end_date": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "price": {
-                    "type": "integer"
-                },
-                "service_name": {
-                    "type": "string"
-                },
-                "start_date": {
-                    "type": "string"
-                },
-                "updated_at": {
-                    "type": "string"
-                },
-                "user_id": {
-                    "type": "string"
-                }
-            }
-        },
-        "internal_service.costResp": {
-            "type": "object",
-            "properties": {
-                "total": {
-                    "type": "integer"
-                }
-            }
         },
-        "internal_service.createReq": {
-            "type": "object",
-            "required": [
+        "/api/v1/audit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает журнал изменений всех подписок с фильтрацией по инициатору, действию и времени, новые записи первыми",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "Журнал изменений",
+                "operationId": "list-audit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Инициатор изменения",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "create",
+                            "update",
+                            "delete",
+                            "restore",
+                            "purge"
+                        ],
+                        "type": "string",
+                        "description": "Действие",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID подписки",
+                        "name": "subscription_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало интервала, включительно (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец интервала, не включительно (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Количество элементов (макс. 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Журнал изменений",
+                        "schema": {
+                            "$ref": "#/definitions/internal_service.auditListResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "403": {
+                        "description": "Требуется роль администратора",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает список подписок с фильтрацией и пагинацией",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Список подписок",
+                "operationId": "list-subscriptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID пользователя",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название сервиса",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Включать удалённые подписки (для администраторов)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Количество элементов (макс. 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Читать с основной БД, а не с реплики",
+                        "name": "X-Read-Your-Writes",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список подписок",
+                        "schema": {
+                            "$ref": "#/definitions/internal_service.listResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписки другого пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создаёт новую подписку на сервис",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Создать новую подписку",
+                "operationId": "create-subscription",
+                "parameters": [
+                    {
+                        "description": "Данные подписки",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_service.createReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Созданная подписка",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "403": {
+                        "description": "Подписка для другого пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/cost": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает суммарную стоимость подписок пользователя или сервиса за указанный период",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Рассчитать стоимость подписок за период",
+                "operationId": "calculate-cost",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Начало периода (формат MM-YYYY)",
+                        "name": "start_period",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода (формат MM-YYYY)",
+                        "name": "end_period",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID пользователя",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название сервиса",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Учитывать удалённые подписки (для администраторов)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Читать с основной БД, а не с реплики",
+                        "name": "X-Read-Your-Writes",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Суммарная стоимость",
+                        "schema": {
+                            "$ref": "#/definitions/internal_service.costResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписки другого пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/events": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Server-Sent Events: события subscription.created, subscription.updated и subscription.deleted организации клиента со всех экземпляров сервиса. Поле id события - его порядковый номер; при переподключении с заголовком Last-Event-ID пропущенные события отдаются из ограниченного буфера. Раз в heartbeat отправляется комментарий-пинг.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Поток изменений подписок",
+                "operationId": "subscription-events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID пользователя",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название сервиса",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер последнего полученного события",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поток событий",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "События другого пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Потоково выгружает все подписки, подходящие под фильтры списка, в CSV, NDJSON или XLSX. Ограничение в 500 записей на выгрузку не действует.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Выгрузить подписки",
+                "operationId": "export-subscriptions",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Формат выгрузки",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID пользователя",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название сервиса",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Включать удалённые подписки (для администраторов)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество элементов (по умолчанию без ограничения)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Читать с основной БД, а не с реплики",
+                        "name": "X-Read-Your-Writes",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл выгрузки",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписки другого пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает информацию о подписке по её уникальному идентификатору",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Получить подписку по ID",
+                "operationId": "get-subscription-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID идентификатор подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Возвращать удалённую подписку (для администраторов)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Информация о подписке",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат ID",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновляет существующую подписку по её ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Обновить подписку",
+                "operationId": "update-subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID идентификатор подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Данные для обновления",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_service.updateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Обновлённая подписка",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Помечает подписку удалённой. Её можно восстановить до истечения срока хранения, после чего она удаляется безвозвратно",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Удалить подписку",
+                "operationId": "delete-subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID идентификатор подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Подписка удалена"
+                    },
+                    "400": {
+                        "description": "Неверный формат ID",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает журнал изменений подписки (создание, обновления, удаление, восстановление), новые записи первыми",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "История изменений подписки",
+                "operationId": "subscription-history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID идентификатор подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Количество элементов (макс. 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Журнал изменений",
+                        "schema": {
+                            "$ref": "#/definitions/internal_service.auditListResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат ID",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Восстанавливает удалённую подписку, если она ещё не была удалена безвозвратно",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Восстановить подписку",
+                "operationId": "restore-subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID идентификатор подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Восстановленная подписка",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат ID",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "403": {
+                        "description": "Требуется роль администратора",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Удалённая подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{user_id}/renewals.ics": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает iCalendar (RFC 5545) с ежемесячно повторяющимся событием для каждой активной подписки пользователя",
+                "produces": [
+                    "text/calendar"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Календарь продлений пользователя",
+                "operationId": "user-renewals-calendar",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Читать с основной БД, а не с реплики",
+                        "name": "X-Read-Your-Writes",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Календарь в формате iCalendar",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат user_id",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Календарь другого пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает всех зарегистрированных получателей (без секретов)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Список получателей событий",
+                "operationId": "list-webhooks",
+                "responses": {
+                    "200": {
+                        "description": "Список получателей",
+                        "schema": {
+                            "$ref": "#/definitions/internal_service.webhookListResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Требуется роль администратора",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Регистрирует URL, на который будут доставляться события выбранных типов. Тело каждой доставки подписывается HMAC-SHA256 на секрете получателя (заголовок X-Webhook-Signature)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Зарегистрировать получателя событий",
+                "operationId": "create-webhook",
+                "parameters": [
+                    {
+                        "description": "Данные получателя",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_service.createWebhookReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Зарегистрированный получатель",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "403": {
+                        "description": "Требуется роль администратора",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "409": {
+                        "description": "Получатель с таким URL уже зарегистрирован",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает получателя событий по ID (без секрета)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Получить получателя событий",
+                "operationId": "get-webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID получателя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Получатель",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат ID",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "403": {
+                        "description": "Требуется роль администратора",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Получатель не найден",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Меняет URL, секрет, типы событий или активность получателя. Отключенному получателю новые события не доставляются, а ожидающие доставки уходят в dead letter",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Обновить получателя событий",
+                "operationId": "update-webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID получателя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Данные для обновления",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_service.updateWebhookReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Обновлённый получатель",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "403": {
+                        "description": "Требуется роль администратора",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Получатель не найден",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "409": {
+                        "description": "Получатель с таким URL уже зарегистрирован",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет получателя вместе с журналом доставок",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Удалить получателя событий",
+                "operationId": "delete-webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID получателя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Получатель удалён"
+                    },
+                    "400": {
+                        "description": "Неверный формат ID",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "403": {
+                        "description": "Требуется роль администратора",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Получатель не найден",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает доставки событий получателю: статус, число попыток, последнюю ошибку и код ответа",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Журнал доставок",
+                "operationId": "list-webhook-deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID получателя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "delivered",
+                            "dead"
+                        ],
+                        "type": "string",
+                        "description": "Статус доставки",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Количество элементов (макс. 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Журнал доставок",
+                        "schema": {
+                            "$ref": "#/definitions/internal_service.deliveryListResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "403": {
+                        "description": "Требуется роль администратора",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Получатель не найден",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{id}/deliveries/{delivery_id}/redeliver": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает доставку в статусе dead в очередь с обнулением счётчика попыток",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Повторить доставку из dead letter",
+                "operationId": "redeliver-webhook-delivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID получателя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID доставки",
+                        "name": "delivery_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Доставка, поставленная в очередь",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.WebhookDelivery"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат ID",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "403": {
+                        "description": "Требуется роль администратора",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "404": {
+                        "description": "Доставка в статусе dead не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Отвечает, пока процесс работает. Зависимости не проверяются",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка живости",
+                "operationId": "liveness",
+                "responses": {
+                    "200": {
+                        "description": "Процесс работает",
+                        "schema": {
+                            "$ref": "#/definitions/echo.Map"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Проверяет соединение с БД, версию схемы БД и фоновые задачи. Во время остановки сервиса возвращает 503 со статусом draining",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка готовности",
+                "operationId": "readiness",
+                "responses": {
+                    "200": {
+                        "description": "Сервис готов",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервис не готов",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_health.Report"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "echo.Map": {
+            "type": "object",
+            "additionalProperties": true
+        },
+        "github_com_untibullet_subscription-service-em_internal_health.Component": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_untibullet_subscription-service-em_internal_health.Report": {
+            "type": "object",
+            "properties": {
+                "components": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_health.Component"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_untibullet_subscription-service-em_internal_models.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Scope"
+                    }
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "nil - доступ к подпискам всех пользователей",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_untibullet_subscription-service-em_internal_models.AuditAction": {
+            "type": "string",
+            "enum": [
+                "create",
+                "update",
+                "delete",
+                "restore",
+                "purge"
+            ],
+            "x-enum-varnames": [
+                "AuditCreate",
+                "AuditUpdate",
+                "AuditDelete",
+                "AuditRestore",
+                "AuditPurge"
+            ]
+        },
+        "github_com_untibullet_subscription-service-em_internal_models.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.AuditAction"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "diff": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.FieldChange"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_untibullet_subscription-service-em_internal_models.DeliveryStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "delivered",
+                "dead"
+            ],
+            "x-enum-varnames": [
+                "DeliveryPending",
+                "DeliveryDelivered",
+                "DeliveryDead"
+            ]
+        },
+        "github_com_untibullet_subscription-service-em_internal_models.Event": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "object"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.EventType"
+                }
+            }
+        },
+        "github_com_untibullet_subscription-service-em_internal_models.EventType": {
+            "type": "string",
+            "enum": [
+                "subscription.created",
+                "subscription.updated",
+                "subscription.deleted",
+                "subscription.renewing"
+            ],
+            "x-enum-varnames": [
+                "EventSubscriptionCreated",
+                "EventSubscriptionUpdated",
+                "EventSubscriptionDeleted",
+                "EventSubscriptionRenewing"
+            ]
+        },
+        "github_com_untibullet_subscription-service-em_internal_models.FieldChange": {
+            "type": "object",
+            "properties": {
+                "after": {},
+                "before": {}
+            }
+        },
+        "github_com_untibullet_subscription-service-em_internal_models.Scope": {
+            "type": "string",
+            "enum": [
+                "subscriptions:read",
+                "subscriptions:write",
+                "reports:read"
+            ],
+            "x-enum-varnames": [
+                "ScopeSubscriptionsRead",
+                "ScopeSubscriptionsWrite",
+                "ScopeReportsRead"
+            ]
+        },
+        "github_com_untibullet_subscription-service-em_internal_models.Subscription": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_untibullet_subscription-service-em_internal_models.Webhook": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.EventType"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_untibullet_subscription-service-em_internal_models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.EventType"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "response_status": {
+                    "type": "integer"
+                },
+                "status": {
+                    "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.DeliveryStatus"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_service.apiKeyListResp": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.APIKey"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_service.apiKeyResp": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "description": "показывается только при выпуске и ротации",
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Scope"
+                    }
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "nil - доступ к подпискам всех пользователей",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_service.auditListResp": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.AuditEntry"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_service.costResp": {
+            "type": "object",
+            "properties": {
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_service.createAPIKeyReq": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "description": "RFC 3339",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Scope"
+                    }
+                },
+                "user_id": {
+                    "description": "ограничить ключ подписками пользователя",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_service.createReq": {
+            "type": "object",
+            "required": [
                 "price",
                 "service_name",
                 "start_date",
@@ -416,6 +2263,37 @@ const docTemplate = `{
                 }
             }
         },
+        "internal_service.createWebhookReq": {
+            "type": "object",
+            "properties": {
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.EventType"
+                    }
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_service.deliveryListResp": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.WebhookDelivery"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
         "internal_service.listResp": {
             "type": "object",
             "properties": {
@@ -430,6 +2308,15 @@ const docTemplate = `{
                 }
             }
         },
+        "internal_service.logLevel": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "description": "debug, info, warn, error",
+                    "type": "string"
+                }
+            }
+        },
         "internal_service.updateReq": {
             "type": "object",
             "properties": {
@@ -450,6 +2337,48 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "internal_service.updateWebhookReq": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.EventType"
+                    }
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_service.webhookListResp": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_untibullet_subscription-service-em_internal_models.Webhook"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "JWT в формате \"Bearer \u003ctoken\u003e\" или API-ключ в формате \"ApiKey \u003cключ\u003e\" (если включена секция auth)",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+	"go.uber.org/zap"
+)
+
+const (
+	// apiKeyTag отличает API-ключи от других секретов (например, в сканерах утечек)
+	apiKeyTag = "ssk_"
+	// длина префикса ключа: метка и 12 hex-символов
+	apiKeyPrefixLen = len(apiKeyTag) + 12
+)
+
+// GenerateAPIKey создает ключ вида ssk_<12 hex>_<секрет>. Возвращает сам ключ,
+// который показывается клиенту один раз, его префикс и хеш для хранения
+func GenerateAPIKey() (key, prefix string, hash []byte, err error) {
+	id := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	prefix = apiKeyTag + hex.EncodeToString(id)
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, hashAPIKey(key), nil
+}
+
+// ключ содержит 256 бит случайности, поэтому медленный хеш паролей не нужен
+func hashAPIKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// APIKeyVerifier проверяет API-ключи сервисных клиентов
+type APIKeyVerifier struct {
+	repo repository.APIKeyRepository
+	log  *zap.Logger
+}
+
+func NewAPIKeyVerifier(repo repository.APIKeyRepository, log *zap.Logger) *APIKeyVerifier {
+	return &APIKeyVerifier{repo: repo, log: log}
+}
+
+// Verify находит ключ по префиксу и сверяет хеш. Отозванные и просроченные
+// ключи не принимаются. Организация клиента до проверки ключа не известна,
+// поэтому ключ ищется среди ключей всех организаций
+func (v *APIKeyVerifier) Verify(ctx context.Context, key string) (*Principal, error) {
+	ctx = reqctx.WithSystem(ctx)
+	if len(key) <= apiKeyPrefixLen || !strings.HasPrefix(key, apiKeyTag) {
+		return nil, fmt.Errorf("%w: malformed api key", ErrUnauthenticated)
+	}
+
+	stored, err := v.repo.GetByPrefix(ctx, key[:apiKeyPrefixLen])
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, fmt.Errorf("%w: unknown api key", ErrUnauthenticated)
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(stored.Hash, hashAPIKey(key)) != 1 {
+		return nil, fmt.Errorf("%w: unknown api key", ErrUnauthenticated)
+	}
+
+	now := time.Now().UTC()
+	if !stored.Active(now) {
+		return nil, fmt.Errorf("%w: api key revoked or expired", ErrUnauthenticated)
+	}
+
+	if err := v.repo.TouchLastUsed(ctx, stored.ID, now); err != nil {
+		v.log.Warn("api key touch failed", zap.String("api_key_id", stored.ID.String()), zap.Error(err))
+	}
+
+	p := &Principal{
+		Subject:  "api-key:" + stored.ID.String(),
+		TenantID: stored.TenantID,
+		APIKey:   true,
+		// пустой, но не nil список: ключ без прав не должен получить все права
+		Scopes: append([]models.Scope{}, stored.Scopes...),
+	}
+	if stored.UserID != nil {
+		p.UserID = stored.UserID.String()
+	}
+	return p, nil
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/untibullet/subscription-service-em/internal/models"
+)
+
+var (
+	// ErrUnauthenticated - токен отсутствует или не прошел проверку
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrKeysUnavailable - не удалось получить ключи проверки подписи;
+	// токен при этом может быть действительным
+	ErrKeysUnavailable = errors.New("signing keys unavailable")
+)
+
+// Principal - аутентифицированный клиент
+type Principal struct {
+	Subject  string
+	UserID   string // идентификатор пользователя, владельца подписок
+	TenantID string // организация клиента; токен без организации отклоняется
+	Admin    bool
+	Claims   jwt.MapClaims
+
+	APIKey bool           // клиент предъявил API-ключ
+	Scopes []models.Scope // права API-ключа; для JWT не ограничены
+}
+
+type ctxKey struct{}
+
+// WithPrincipal сохраняет в контексте аутентифицированного клиента
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext возвращает аутентифицированного клиента или nil
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(ctxKey{}).(*Principal)
+	return p
+}
+
+// Config - параметры проверки токенов
+type Config struct {
+	Issuer     string   // пустой - не проверяется
+	Audience   string   // пустой - не проверяется
+	Algorithms []string // допустимые алгоритмы подписи
+	ClockSkew  time.Duration
+
+	UserIDClaim string // claim с идентификатором пользователя
+	TenantClaim string // claim с организацией пользователя
+	RolesClaim  string // claim со списком ролей (строка или массив строк)
+	AdminRole   string
+}
+
+// Authenticator проверяет bearer-JWT
+type Authenticator struct {
+	keys   KeySource
+	parser *jwt.Parser
+	cfg    Config
+}
+
+func NewAuthenticator(keys KeySource, cfg Config) *Authenticator {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(cfg.Algorithms),
+		jwt.WithLeeway(cfg.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	return &Authenticator{keys: keys, parser: jwt.NewParser(opts...), cfg: cfg}
+}
+
+// Authenticate проверяет подпись и стандартные claims токена
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return a.keyFor(ctx, t)
+	})
+	if err != nil {
+		if errors.Is(err, ErrKeysUnavailable) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+
+	sub, err := claims.GetSubject()
+	if err != nil || sub == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrUnauthenticated)
+	}
+
+	userID, _ := claims[a.cfg.UserIDClaim].(string)
+	tenantID, _ := claims[a.cfg.TenantClaim].(string)
+
+	return &Principal{
+		Subject:  sub,
+		UserID:   userID,
+		TenantID: tenantID,
+		Admin:    hasRole(claims[a.cfg.RolesClaim], a.cfg.AdminRole),
+		Claims:   claims,
+	}, nil
+}
+
+func hasRole(claim interface{}, role string) bool {
+	switch v := claim.(type) {
+	case string:
+		return v == role
+	case []interface{}:
+		for _, r := range v {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// keyFor выбирает ключ по kid из заголовка токена. Без kid, как и для ключей
+// без ID, подходят все ключи, совместимые с алгоритмом; их jwt перебирает сам
+func (a *Authenticator) keyFor(ctx context.Context, t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	alg := t.Method.Alg()
+
+	keys, err := a.keys.Keys(ctx, kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKeysUnavailable, err)
+	}
+
+	set := jwt.VerificationKeySet{}
+	for _, k := range keys {
+		if kid != "" && k.ID != "" && k.ID != kid {
+			continue
+		}
+		if k.Alg != "" && k.Alg != alg {
+			continue
+		}
+		// ключ должен соответствовать семейству алгоритма, иначе возможна
+		// подмена алгоритма (например, RSA-ключ как секрет HS256)
+		if !compatible(t.Method, k.Key) {
+			continue
+		}
+		set.Keys = append(set.Keys, k.Key)
+	}
+	if len(set.Keys) == 0 {
+		return nil, fmt.Errorf("no key for kid %q and alg %s", kid, alg)
+	}
+
+	return set, nil
+}
+
+func compatible(method jwt.SigningMethod, key interface{}) bool {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok := key.(*rsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodECDSA:
+		_, ok := key.(*ecdsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodHMAC:
+		_, ok := key.([]byte)
+		return ok
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// failingKeys - источник ключей, который недоступен
+type failingKeys struct{}
+
+func (failingKeys) Keys(context.Context, string) ([]Key, error) {
+	return nil, errors.New("jwks endpoint is down")
+}
+
+func testAuthConfig() Config {
+	return Config{
+		Issuer:      "https://issuer.test",
+		Audience:    "subscriptions",
+		Algorithms:  []string{"RS256", "ES256", "HS256"},
+		ClockSkew:   30 * time.Second,
+		UserIDClaim: "user_id",
+		TenantClaim: "tenant_id",
+		RolesClaim:  "roles",
+		AdminRole:   "admin",
+	}
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"sub":       "user-1",
+		"iss":       "https://issuer.test",
+		"aud":       "subscriptions",
+		"iat":       now.Unix(),
+		"exp":       now.Add(time.Hour).Unix(),
+		"user_id":   "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		"tenant_id": "acme",
+	}
+}
+
+// with возвращает копию claims с изменениями; nil удаляет claim
+func with(claims jwt.MapClaims, changes jwt.MapClaims) jwt.MapClaims {
+	out := jwt.MapClaims{}
+	for k, v := range claims {
+		out[k] = v
+	}
+	for k, v := range changes {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys := newTestKeys(t)
+	other := newTestKeys(t)
+	source := KeySources{
+		StaticKeys{
+			{ID: "rsa-1", Alg: "RS256", Key: &keys.rsa.PublicKey},
+			{ID: "ec-1", Key: &keys.ec.PublicKey},
+		},
+		// общий секрет задается без ID
+		StaticKeys{{Alg: "HS256", Key: keys.secret}},
+	}
+	authn := NewAuthenticator(source, testAuthConfig())
+	claims := validClaims()
+
+	tests := []struct {
+		name      string
+		token     string
+		wantErr   error
+		wantAdmin bool
+	}{
+		{name: "rs256 with kid", token: sign(t, jwt.SigningMethodRS256, "rsa-1", claims, keys.rsa)},
+		{name: "rs256 without kid", token: sign(t, jwt.SigningMethodRS256, "", claims, keys.rsa)},
+		{name: "es256", token: sign(t, jwt.SigningMethodES256, "ec-1", claims, keys.ec)},
+		{name: "hs256 without kid", token: sign(t, jwt.SigningMethodHS256, "", claims, keys.secret)},
+		{name: "hs256 with kid matches key without id", token: sign(t, jwt.SigningMethodHS256, "shared", claims, keys.secret)},
+		{
+			name:      "admin role in list",
+			token:     sign(t, jwt.SigningMethodRS256, "rsa-1", with(claims, jwt.MapClaims{"roles": []string{"viewer", "admin"}}), keys.rsa),
+			wantAdmin: true,
+		},
+		{
+			name:      "admin role as string",
+			token:     sign(t, jwt.SigningMethodRS256, "rsa-1", with(claims, jwt.MapClaims{"roles": "admin"}), keys.rsa),
+			wantAdmin: true,
+		},
+		{name: "signed by unknown key", token: sign(t, jwt.SigningMethodRS256, "rsa-1", claims, other.rsa), wantErr: ErrUnauthenticated},
+		{name: "unknown kid", token: sign(t, jwt.SigningMethodES256, "ec-2", claims, keys.ec), wantErr: ErrUnauthenticated},
+		{name: "kid of key with another algorithm", token: sign(t, jwt.SigningMethodES256, "rsa-1", claims, keys.ec), wantErr: ErrUnauthenticated},
+		{name: "wrong hmac secret", token: sign(t, jwt.SigningMethodHS256, "", claims, []byte("another-secret-another-secret-00")), wantErr: ErrUnauthenticated},
+		{name: "algorithm not allowed", token: sign(t, jwt.SigningMethodRS512, "rsa-1", claims, keys.rsa), wantErr: ErrUnauthenticated},
+		{name: "expired", token: sign(t, jwt.SigningMethodRS256, "rsa-1", with(claims, jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}), keys.rsa), wantErr: ErrUnauthenticated},
+		{name: "expired within clock skew", token: sign(t, jwt.SigningMethodRS256, "rsa-1", with(claims, jwt.MapClaims{"exp": time.Now().Add(-10 * time.Second).Unix()}), keys.rsa)},
+		{name: "without exp", token: sign(t, jwt.SigningMethodRS256, "rsa-1", with(claims, jwt.MapClaims{"exp": nil}), keys.rsa), wantErr: ErrUnauthenticated},
+		{name: "not yet valid", token: sign(t, jwt.SigningMethodRS256, "rsa-1", with(claims, jwt.MapClaims{"nbf": time.Now().Add(time.Hour).Unix()}), keys.rsa), wantErr: ErrUnauthenticated},
+		{name: "wrong issuer", token: sign(t, jwt.SigningMethodRS256, "rsa-1", with(claims, jwt.MapClaims{"iss": "https://evil.test"}), keys.rsa), wantErr: ErrUnauthenticated},
+		{name: "wrong audience", token: sign(t, jwt.SigningMethodRS256, "rsa-1", with(claims, jwt.MapClaims{"aud": "billing"}), keys.rsa), wantErr: ErrUnauthenticated},
+		{name: "without subject", token: sign(t, jwt.SigningMethodRS256, "rsa-1", with(claims, jwt.MapClaims{"sub": nil}), keys.rsa), wantErr: ErrUnauthenticated},
+		{name: "malformed", token: "not.a.token", wantErr: ErrUnauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := authn.Authenticate(context.Background(), tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if p.Subject != "user-1" || p.UserID != "60601fee-2bf1-4721-ae6f-7636e79a0cba" || p.TenantID != "acme" {
+				t.Errorf("principal = %+v", p)
+			}
+			if p.Admin != tt.wantAdmin {
+				t.Errorf("Admin = %v, want %v", p.Admin, tt.wantAdmin)
+			}
+		})
+	}
+}
+
+// Открытый RSA-ключ не должен приниматься как секрет HS256
+func TestAuthenticateAlgorithmConfusion(t *testing.T) {
+	keys := newTestKeys(t)
+	authn := NewAuthenticator(StaticKeys{{ID: "rsa-1", Key: &keys.rsa.PublicKey}}, testAuthConfig())
+
+	modulus := keys.rsa.PublicKey.N.Bytes()
+	token := sign(t, jwt.SigningMethodHS256, "rsa-1", validClaims(), modulus)
+	if _, err := authn.Authenticate(context.Background(), token); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("Authenticate() error = %v, want %v", err, ErrUnauthenticated)
+	}
+}
+
+func TestAuthenticateKeysUnavailable(t *testing.T) {
+	keys := newTestKeys(t)
+	authn := NewAuthenticator(failingKeys{}, testAuthConfig())
+
+	token := sign(t, jwt.SigningMethodRS256, "rsa-1", validClaims(), keys.rsa)
+	_, err := authn.Authenticate(context.Background(), token)
+	if !errors.Is(err, ErrKeysUnavailable) {
+		t.Fatalf("Authenticate() error = %v, want %v", err, ErrKeysUnavailable)
+	}
+	if errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("Authenticate() error = %v, must not report bad credentials", err)
+	}
+}
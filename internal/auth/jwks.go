@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// minJWKSRefetch - как часто можно перечитывать JWKS по URL при неизвестном kid
+const minJWKSRefetch = time.Minute
+
+// jwk - ключ в формате RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// oct
+	K string `json:"k"`
+}
+
+// Key - ключ проверки подписи: *rsa.PublicKey, *ecdsa.PublicKey или []byte
+type Key struct {
+	ID  string // пустой - подходит для токена с любым kid
+	Alg string // пустой, если ключ не ограничен алгоритмом
+	Key interface{}
+}
+
+// ParseJWKS разбирает набор ключей. Ключи, предназначенные не для подписи,
+// и ключи неподдерживаемых типов пропускаются
+func ParseJWKS(data []byte) ([]Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse jwks: %w", err)
+	}
+
+	keys := make([]Key, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: %w", k.Kid, err)
+		}
+		if key == nil {
+			continue
+		}
+		keys = append(keys, Key{ID: k.Kid, Alg: k.Alg, Key: key})
+	}
+
+	return keys, nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid e: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid e")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y: %w", err)
+		}
+		point := append(append([]byte{4}, x...), y...)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, fmt.Errorf("invalid k: %w", err)
+		}
+		return secret, nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// KeySource - источник ключей проверки подписи
+type KeySource interface {
+	Keys(ctx context.Context, kid string) ([]Key, error)
+}
+
+// StaticKeys - неизменяемый набор ключей (JWKS из файла, общий секрет)
+type StaticKeys []Key
+
+func (s StaticKeys) Keys(context.Context, string) ([]Key, error) {
+	return s, nil
+}
+
+// LoadJWKSFile читает JWKS из файла
+func LoadJWKSFile(path string) (StaticKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks file: %w", err)
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RemoteJWKS - JWKS по URL. Набор обновляется каждые refresh, а при
+// неизвестном kid - не чаще раза в минуту, чтобы подхватить ротацию ключей.
+// Набор запрашивается одним запросом на всех; пока он выполняется, остальные
+// запросы проверяются по прежнему набору, если в нем есть нужный ключ
+type RemoteJWKS struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+	log     *zap.Logger
+
+	fetching  singleflight.Group
+	mu        sync.Mutex
+	keys      []Key
+	fetchedAt time.Time
+}
+
+func NewRemoteJWKS(url string, refresh time.Duration, log *zap.Logger) *RemoteJWKS {
+	return &RemoteJWKS{
+		url:     url,
+		refresh: refresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		log:     log,
+	}
+}
+
+func (r *RemoteJWKS) Keys(ctx context.Context, kid string) ([]Key, error) {
+	r.mu.Lock()
+	keys, age := r.keys, time.Since(r.fetchedAt)
+	r.mu.Unlock()
+
+	known := keys != nil && (kid == "" || hasKey(keys, kid))
+	switch {
+	case keys != nil && age <= r.refresh && (known || age <= minJWKSRefetch):
+		return keys, nil
+	case known:
+		// набор устарел, но ключ в нем есть: обновляем в фоне
+		r.fetching.DoChan("", r.update)
+		return keys, nil
+	}
+
+	select {
+	case res := <-r.fetching.DoChan("", r.update):
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]Key), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// update запрашивает набор ключей. Запрос не зависит от контекста отдельного
+// запроса клиента: его результат нужен всем ожидающим. При сбое остается
+// прежний набор
+func (r *RemoteJWKS) update() (interface{}, error) {
+	keys, err := r.fetch(context.Background())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fetchedAt = time.Now()
+	if err != nil {
+		if r.keys == nil {
+			return nil, err
+		}
+		r.log.Warn("jwks refresh failed", zap.String("url", r.url), zap.Error(err))
+		return r.keys, nil
+	}
+	r.keys = keys
+	return keys, nil
+}
+
+func (r *RemoteJWKS) fetch(ctx context.Context) ([]Key, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build jwks request: %w", err)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks: %w", err)
+	}
+
+	return ParseJWKS(data)
+}
+
+func hasKey(keys []Key, kid string) bool {
+	for _, k := range keys {
+		if k.ID == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// KeySources объединяет ключи нескольких источников
+type KeySources []KeySource
+
+func (s KeySources) Keys(ctx context.Context, kid string) ([]Key, error) {
+	var all []Key
+	for _, src := range s {
+		keys, err := src.Keys(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, keys...)
+	}
+	return all, nil
+}
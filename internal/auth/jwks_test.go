@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// testKeys - ключи, сгенерированные для тестов
+type testKeys struct {
+	rsa    *rsa.PrivateKey
+	ec     *ecdsa.PrivateKey
+	secret []byte
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeys{rsa: rsaKey, ec: ecKey, secret: []byte("0123456789abcdef0123456789abcdef")}
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jwk {
+	return jwk{Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256", N: b64(key.N.Bytes()), E: b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) jwk {
+	point, _ := key.Bytes()
+	size := (len(point) - 1) / 2
+	return jwk{Kty: "EC", Kid: kid, Crv: "P-256", X: b64(point[1 : 1+size]), Y: b64(point[1+size:])}
+}
+
+func jwksJSON(t *testing.T, keys ...jwk) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseJWKS(t *testing.T) {
+	keys := newTestKeys(t)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantIDs []string
+		wantErr bool
+	}{
+		{
+			name:    "rsa, ec and oct keys",
+			data:    jwksJSON(t, rsaJWK("rsa-1", &keys.rsa.PublicKey), ecJWK("ec-1", &keys.ec.PublicKey), jwk{Kty: "oct", Kid: "hs-1", K: b64(keys.secret)}),
+			wantIDs: []string{"rsa-1", "ec-1", "hs-1"},
+		},
+		{
+			name:    "encryption keys are skipped",
+			data:    jwksJSON(t, rsaJWK("sig", &keys.rsa.PublicKey), jwk{Kty: "RSA", Kid: "enc", Use: "enc", N: "AQAB", E: "AQAB"}),
+			wantIDs: []string{"sig"},
+		},
+		{
+			name:    "unsupported key types are skipped",
+			data:    jwksJSON(t, jwk{Kty: "OKP", Kid: "ed"}, rsaJWK("rsa", &keys.rsa.PublicKey)),
+			wantIDs: []string{"rsa"},
+		},
+		{
+			name:    "empty set",
+			data:    []byte(`{"keys":[]}`),
+			wantIDs: []string{},
+		},
+		{
+			name:    "unsupported curve",
+			data:    jwksJSON(t, jwk{Kty: "EC", Kid: "ec", Crv: "secp256k1", X: "AA", Y: "AA"}),
+			wantErr: true,
+		},
+		{
+			name:    "point not on curve",
+			data:    jwksJSON(t, jwk{Kty: "EC", Kid: "ec", Crv: "P-256", X: b64(make([]byte, 32)), Y: b64(make([]byte, 32))}),
+			wantErr: true,
+		},
+		{
+			name:    "invalid modulus encoding",
+			data:    jwksJSON(t, jwk{Kty: "RSA", Kid: "rsa", N: "!!", E: "AQAB"}),
+			wantErr: true,
+		},
+		{
+			name:    "empty exponent",
+			data:    jwksJSON(t, jwk{Kty: "RSA", Kid: "rsa", N: "AQAB", E: ""}),
+			wantErr: true,
+		},
+		{
+			name:    "malformed json",
+			data:    []byte(`{"keys":`),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseJWKS(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseJWKS() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			ids := make([]string, 0, len(got))
+			for _, k := range got {
+				ids = append(ids, k.ID)
+			}
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("key ids = %v, want %v", ids, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Fatalf("key ids = %v, want %v", ids, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestParseJWKSKeyTypes(t *testing.T) {
+	keys := newTestKeys(t)
+	got, err := ParseJWKS(jwksJSON(t, rsaJWK("rsa", &keys.rsa.PublicKey), ecJWK("ec", &keys.ec.PublicKey), jwk{Kty: "oct", Kid: "hs", K: b64(keys.secret)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if k, ok := got[0].Key.(*rsa.PublicKey); !ok || !k.Equal(&keys.rsa.PublicKey) {
+		t.Errorf("rsa key = %#v, want generated public key", got[0].Key)
+	}
+	if got[0].Alg != "RS256" {
+		t.Errorf("rsa key alg = %q, want RS256", got[0].Alg)
+	}
+	if k, ok := got[1].Key.(*ecdsa.PublicKey); !ok || !k.Equal(&keys.ec.PublicKey) {
+		t.Errorf("ec key = %#v, want generated public key", got[1].Key)
+	}
+	if k, ok := got[2].Key.([]byte); !ok || string(k) != string(keys.secret) {
+		t.Errorf("oct key = %#v, want secret", got[2].Key)
+	}
+}
+
+func TestRemoteJWKSFetchesOnce(t *testing.T) {
+	keys := newTestKeys(t)
+	data := jwksJSON(t, rsaJWK("rsa", &keys.rsa.PublicKey))
+
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+		<-release
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	src := NewRemoteJWKS(srv.URL, time.Hour, zap.NewNop())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Go(func() {
+			got, err := src.Keys(context.Background(), "rsa")
+			if err == nil && len(got) != 1 {
+				t.Errorf("Keys() returned %d keys, want 1", len(got))
+			}
+			errs <- err
+		})
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Keys() error = %v", err)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("jwks fetched %d times, want 1", n)
+	}
+
+	// свежий набор с известным kid отдается без запроса
+	if _, err := src.Keys(context.Background(), "rsa"); err != nil {
+		t.Fatal(err)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("jwks fetched %d times, want 1", n)
+	}
+}
+
+func TestRemoteJWKSUnavailable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	src := NewRemoteJWKS(srv.URL, time.Hour, zap.NewNop())
+	if _, err := src.Keys(context.Background(), "rsa"); err == nil {
+		t.Fatal("Keys() error = nil, want fetch error")
+	}
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+)
+
+// ClientCert - клиент, предъявивший при mTLS сертификат, подписанный CA из
+// server.tls.client_ca_file
+type ClientCert struct {
+	Subject  string // CommonName
+	DNSNames []string
+	URIs     []string // например, SPIFFE ID
+	Serial   string
+}
+
+type clientCertKey struct{}
+
+// NewClientCert возвращает клиента из проверенной цепочки сертификатов
+// соединения или nil, если клиент сертификат не предъявил
+func NewClientCert(state *tls.ConnectionState) *ClientCert {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	leaf := state.VerifiedChains[0][0]
+	cert := &ClientCert{
+		Subject:  leaf.Subject.CommonName,
+		DNSNames: leaf.DNSNames,
+		Serial:   leaf.SerialNumber.String(),
+	}
+	for _, u := range leaf.URIs {
+		cert.URIs = append(cert.URIs, u.String())
+	}
+	return cert
+}
+
+// WithClientCert сохраняет в контексте клиента mTLS
+func WithClientCert(ctx context.Context, cert *ClientCert) context.Context {
+	return context.WithValue(ctx, clientCertKey{}, cert)
+}
+
+// ClientCertFromContext возвращает клиента mTLS или nil
+func ClientCertFromContext(ctx context.Context) *ClientCert {
+	cert, _ := ctx.Value(clientCertKey{}).(*ClientCert)
+	return cert
+}
@@ -0,0 +1,158 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// reloadDelay - пауза после последнего изменения файлов перед их чтением.
+// Замена сертификата (в том числе секрета Kubernetes) порождает несколько
+// событий подряд, и читать файлы между ними бессмысленно
+const reloadDelay = 500 * time.Millisecond
+
+// Files - файлы сертификата сервера и CA клиентов
+type Files struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string // пустой - сертификат клиента не запрашивается
+}
+
+// Reloader выдает TLS-конфигурацию с текущими сертификатами и перечитывает
+// файлы при их изменении. Если новые файлы не читаются, остаются прежние
+// сертификаты
+type Reloader struct {
+	files Files
+	log   *zap.Logger
+
+	current atomic.Pointer[tls.Config]
+	loaded  []byte // содержимое загруженных сертификатов, чтобы не перезагружать их без изменений
+}
+
+func NewReloader(files Files, log *zap.Logger) (*Reloader, error) {
+	r := &Reloader{files: files, log: log}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig возвращает конфигурацию для http.Server: TLS 1.2+, HTTP/2 и
+// сертификаты из последней успешной загрузки при каждом рукопожатии.
+// Сертификат клиента проверяется, если предъявлен, но не требуется: иначе
+// проверки оркестратора не прошли бы. Требовать его на путях API должен
+// обработчик запросов
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+	}
+}
+
+func (r *Reloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.files.CertFile, r.files.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{cert},
+	}
+	loaded := slices.Concat(cert.Certificate...)
+
+	if r.files.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.files.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", r.files.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		loaded = append(loaded, pem...)
+	}
+
+	if bytes.Equal(loaded, r.loaded) {
+		return nil
+	}
+	r.current.Store(cfg)
+	r.loaded = loaded
+	r.log.Info("tls certificates loaded",
+		zap.String("subject", cert.Leaf.Subject.String()),
+		zap.Time("not_after", cert.Leaf.NotAfter),
+		zap.Bool("client_ca", cfg.ClientCAs != nil),
+	)
+	return nil
+}
+
+// dirs - каталоги файлов. Следить приходится за каталогами: файлы заменяются
+// переименованием, а Kubernetes подменяет символическую ссылку на каталог
+func (r *Reloader) dirs() []string {
+	var dirs []string
+	for _, f := range []string{r.files.CertFile, r.files.KeyFile, r.files.ClientCAFile} {
+		if f == "" {
+			continue
+		}
+		if dir := filepath.Dir(f); !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// Run перечитывает сертификаты при изменении файлов до отмены ctx
+func (r *Reloader) Run(ctx context.Context) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		r.log.Error("tls certificate reload is disabled", zap.Error(err))
+		return
+	}
+	defer watcher.Close()
+	for _, dir := range r.dirs() {
+		if err := watcher.Add(dir); err != nil {
+			r.log.Error("tls certificate reload is disabled", zap.String("dir", dir), zap.Error(err))
+			return
+		}
+	}
+	r.log.Info("tls certificate watcher started", zap.Strings("dirs", r.dirs()))
+
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			r.log.Info("tls certificate watcher stopped")
+			return
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			reload = time.After(reloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			r.log.Error("tls certificate watcher error", zap.Error(err))
+		case <-reload:
+			reload = nil
+			if err := r.load(); err != nil {
+				r.log.Error("tls certificate reload failed, keeping current certificates", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/auth"
+	"github.com/untibullet/subscription-service-em/internal/service"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// testCA - удостоверяющий центр, выпускающий сертификаты для тестов
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue выпускает сертификат сервера для localhost или сертификат клиента
+// и возвращает его и ключ в PEM
+func (ca *testCA) issue(t *testing.T, name string, client bool) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if client {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// replace атомарно заменяет файл, как это делают cert-manager и Kubernetes
+func replace(t *testing.T, path string, data []byte) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testFiles записывает сертификат сервера name и, если clientCA не nil,
+// CA клиентов во временный каталог
+func testFiles(t *testing.T, ca *testCA, name string, clientCA *testCA) Files {
+	t.Helper()
+	dir := t.TempDir()
+	files := Files{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+	certPEM, keyPEM := ca.issue(t, name, false)
+	replace(t, files.CertFile, certPEM)
+	replace(t, files.KeyFile, keyPEM)
+	if clientCA != nil {
+		files.ClientCAFile = filepath.Join(dir, "client-ca.crt")
+		replace(t, files.ClientCAFile, clientCA.pem)
+	}
+	return files
+}
+
+// startServer запускает HTTPS-сервер с конфигурацией Reloader и проверкой
+// сертификата клиента на путях, кроме /healthz
+func startServer(t *testing.T, r *Reloader) *httptest.Server {
+	t.Helper()
+	e := echo.New()
+	e.Use(service.ClientCertificate(zap.NewNop(), "/healthz"))
+	e.GET("/healthz", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.GET("/api/v1/whoami", func(c echo.Context) error {
+		return c.String(http.StatusOK, auth.ClientCertFromContext(c.Request().Context()).Subject)
+	})
+
+	srv := httptest.NewUnstartedServer(e)
+	srv.TLS = r.TLSConfig()
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// get выполняет запрос в новом соединении и возвращает сертификат сервера
+func get(t *testing.T, srv *httptest.Server, ca *testCA, clientCert *tls.Certificate, path string) (*http.Response, string, error) {
+	t.Helper()
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	cfg := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+	if clientCert != nil {
+		// клиент tls не предъявляет сертификат, не подписанный CA из запроса
+		// сервера; предъявляем его в любом случае, как сделал бы атакующий
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return clientCert, nil
+		}
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, DisableKeepAlives: true}}
+
+	resp, err := client.Get(srv.URL + path)
+	if err != nil {
+		return nil, "", err
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp, resp.TLS.PeerCertificates[0].Subject.CommonName, nil
+}
+
+func clientCertificate(t *testing.T, ca *testCA, name string) *tls.Certificate {
+	t.Helper()
+	cert, err := tls.X509KeyPair(ca.issue(t, name, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &cert
+}
+
+// waitLog ждет сообщения msg в логе
+func waitLog(t *testing.T, logs *observer.ObservedLogs, msg string, count int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for logs.FilterMessage(msg).Len() < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("log %q appeared %d times, want %d", msg, logs.FilterMessage(msg).Len(), count)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func runReloader(t *testing.T, files Files) (*Reloader, *observer.ObservedLogs) {
+	t.Helper()
+	core, logs := observer.New(zap.InfoLevel)
+	r, err := NewReloader(files, zap.New(core))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	waitLog(t, logs, "tls certificate watcher started", 1)
+	return r, logs
+}
+
+func TestReloaderSwapsCertificate(t *testing.T) {
+	ca := newTestCA(t, "test CA")
+	files := testFiles(t, ca, "server-1", nil)
+	r, logs := runReloader(t, files)
+	srv := startServer(t, r)
+
+	if _, cn, err := get(t, srv, ca, nil, "/healthz"); err != nil || cn != "server-1" {
+		t.Fatalf("before swap: cn = %q, err = %v", cn, err)
+	}
+
+	// сертификат и ключ заменяются по очереди: между событиями пара не сходится,
+	// и только пауза перед чтением не дает принять ее за ошибку
+	certPEM, keyPEM := ca.issue(t, "server-2", false)
+	replace(t, files.CertFile, certPEM)
+	time.Sleep(reloadDelay / 5)
+	replace(t, files.KeyFile, keyPEM)
+	waitLog(t, logs, "tls certificates loaded", 2)
+
+	if n := logs.FilterMessage("tls certificate reload failed, keeping current certificates").Len(); n != 0 {
+		t.Errorf("reload failed %d times while files were being replaced", n)
+	}
+	if _, cn, err := get(t, srv, ca, nil, "/healthz"); err != nil || cn != "server-2" {
+		t.Fatalf("after swap: cn = %q, err = %v", cn, err)
+	}
+}
+
+func TestReloaderKeepsCertificateOnInvalidFile(t *testing.T) {
+	ca := newTestCA(t, "test CA")
+	files := testFiles(t, ca, "server-1", nil)
+	r, logs := runReloader(t, files)
+	srv := startServer(t, r)
+
+	replace(t, files.CertFile, []byte("not a certificate"))
+	waitLog(t, logs, "tls certificate reload failed, keeping current certificates", 1)
+
+	if _, cn, err := get(t, srv, ca, nil, "/healthz"); err != nil || cn != "server-1" {
+		t.Fatalf("after invalid replacement: cn = %q, err = %v", cn, err)
+	}
+}
+
+func TestReloaderClientCA(t *testing.T) {
+	ca := newTestCA(t, "server CA")
+	clientCA := newTestCA(t, "client CA")
+	untrusted := newTestCA(t, "untrusted CA")
+	r, _ := runReloader(t, testFiles(t, ca, "server", clientCA))
+	srv := startServer(t, r)
+
+	tests := []struct {
+		name       string
+		cert       *tls.Certificate
+		path       string
+		wantStatus int
+		wantBody   string
+		wantErr    bool // рукопожатие не проходит
+	}{
+		{name: "trusted client", cert: clientCertificate(t, clientCA, "billing"), path: "/api/v1/whoami", wantStatus: http.StatusOK, wantBody: "billing"},
+		{name: "no certificate", path: "/api/v1/whoami", wantStatus: http.StatusUnauthorized, wantBody: "client certificate required"},
+		{name: "no certificate on public path", path: "/healthz", wantStatus: http.StatusOK},
+		{name: "untrusted certificate", cert: clientCertificate(t, untrusted, "intruder"), path: "/healthz", wantErr: true},
+		{name: "certificate signed by server CA", cert: clientCertificate(t, ca, "server-ca-client"), path: "/api/v1/whoami", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, _, err := get(t, srv, ca, tt.cert, tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GET %s status = %d, want handshake error", tt.path, resp.StatusCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestNewReloaderErrors(t *testing.T) {
+	ca := newTestCA(t, "test CA")
+
+	tests := []struct {
+		name    string
+		change  func(t *testing.T, files *Files)
+		wantErr string
+	}{
+		{
+			name:    "missing key",
+			change:  func(_ *testing.T, files *Files) { files.KeyFile += ".missing" },
+			wantErr: "failed to load server certificate",
+		},
+		{
+			name:    "missing client CA",
+			change:  func(_ *testing.T, files *Files) { files.ClientCAFile = files.CertFile + ".missing" },
+			wantErr: "failed to read client CA",
+		},
+		{
+			name: "client CA without certificates",
+			change: func(t *testing.T, files *Files) {
+				files.ClientCAFile = filepath.Join(filepath.Dir(files.CertFile), "empty.crt")
+				replace(t, files.ClientCAFile, []byte("# empty\n"))
+			},
+			wantErr: "no certificates found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := testFiles(t, ca, "server", nil)
+			tt.change(t, &files)
+			_, err := NewReloader(files, zap.NewNop())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("NewReloader() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,36 +2,230 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"reflect"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Logger   LoggerConfig   `mapstructure:"logger"`
-	Env      string         `mapstructure:"env"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	Logger    LoggerConfig    `mapstructure:"logger"`
+	Retention RetentionConfig `mapstructure:"retention"`
+	Webhooks  WebhooksConfig  `mapstructure:"webhooks"`
+	Outbox    OutboxConfig    `mapstructure:"outbox"`
+	Events    EventsConfig    `mapstructure:"events"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	Tenancy   TenancyConfig   `mapstructure:"tenancy"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	Health    HealthConfig    `mapstructure:"health"`
+	Metrics   MetricsConfig   `mapstructure:"metrics"`
+	Tracing   TracingConfig   `mapstructure:"tracing"`
+	CORS      CORSConfig      `mapstructure:"cors"`
+	Env       string          `mapstructure:"env"`
 }
 
 type ServerConfig struct {
-	Port string `mapstructure:"port"`
-	Host string `mapstructure:"host"`
+	Port              string        `mapstructure:"port"`
+	Host              string        `mapstructure:"host"`
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`        // чтение запроса целиком, включая тело
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"` // чтение заголовков запроса
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`       // запись ответа; не действует на поток событий и выгрузку
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`        // простой keep-alive соединения
+	MaxHeaderBytes    int           `mapstructure:"max_header_bytes"`
+	ShutdownTimeout   time.Duration `mapstructure:"shutdown_timeout"` // сколько ждать завершения запросов при остановке
+	TLS               TLSConfig     `mapstructure:"tls"`
+}
+
+// TLSConfig - HTTPS без терминирующего прокси. Пустой cert_file - HTTP.
+// Файлы перечитываются при изменении без перезапуска
+type TLSConfig struct {
+	CertFile     string `mapstructure:"cert_file"`
+	KeyFile      string `mapstructure:"key_file"`
+	ClientCAFile string `mapstructure:"client_ca_file"` // непустой - клиенты API предъявляют сертификат (mTLS)
 }
 
 type DatabaseConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
 	User     string `mapstructure:"user"`
-	Password string `mapstructure:"password"`
+	Password string `mapstructure:"password" secret:"true"`
 	Name     string `mapstructure:"name"`
-	SSLMode  string `mapstructure:"sslmode"`
+	SSLMode  string `mapstructure:"sslmode" enum:"disable,allow,prefer,require,verify-ca,verify-full"`
+	// QueryComments добавляет к SQL комментарий с X-Request-ID для pg_stat_activity
+	QueryComments bool `mapstructure:"query_comments"`
+	// AutoMigrate применяет встроенные миграции при старте под advisory lock
+	AutoMigrate bool `mapstructure:"auto_migrate"`
+
+	// Пул соединений; нулевые значения - умолчания pgxpool
+	MaxConns          int32         `mapstructure:"max_conns"`
+	MinConns          int32         `mapstructure:"min_conns"`
+	MaxConnLifetime   time.Duration `mapstructure:"max_conn_lifetime"`
+	MaxConnIdleTime   time.Duration `mapstructure:"max_conn_idle_time"`
+	HealthCheckPeriod time.Duration `mapstructure:"health_check_period"`
+	StatementTimeout  time.Duration `mapstructure:"statement_timeout"` // 0 - без ограничения
+	ApplicationName   string        `mapstructure:"application_name"`  // видно в pg_stat_activity
+
+	// Подключение при старте: попытки с экспоненциальной задержкой от
+	// ConnectBackoff до ConnectMaxBackoff, пока Postgres недоступен
+	ConnectAttempts   int           `mapstructure:"connect_attempts"`
+	ConnectBackoff    time.Duration `mapstructure:"connect_backoff"`
+	ConnectMaxBackoff time.Duration `mapstructure:"connect_max_backoff"`
+
+	// Реплики для List, расчета стоимости и сводных показателей: параметры
+	// подключения key=value, дополняющие параметры основной БД (обычно host и
+	// port). Реплика с отставанием больше ReplicaMaxLag не используется.
+	// Параметры могут содержать пароль, поэтому скрываются как секрет
+	Replicas             []string      `mapstructure:"replicas" secret:"true"`
+	ReplicaMaxLag        time.Duration `mapstructure:"replica_max_lag"`
+	ReplicaCheckInterval time.Duration `mapstructure:"replica_check_interval"`
 }
 
 type LoggerConfig struct {
-	Level  string `mapstructure:"level"`
-	Format string `mapstructure:"format"`
+	Level            string         `mapstructure:"level" enum:"debug,info,warn,error"`
+	Format           string         `mapstructure:"format" enum:"json,console"`
+	OutputPaths      []string       `mapstructure:"output_paths"`
+	ErrorOutputPaths []string       `mapstructure:"error_output_paths"`
+	Sampling         SamplingConfig `mapstructure:"sampling"`
+}
+
+// SamplingConfig - сэмплирование одинаковых сообщений: в течение секунды
+// пишутся первые Initial, затем каждое Thereafter-е. Initial = 0 выключает
+type SamplingConfig struct {
+	Initial    int `mapstructure:"initial"`
+	Thereafter int `mapstructure:"thereafter"`
+}
+
+// RetentionConfig - хранение мягко удаленных подписок
+type RetentionConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Period   time.Duration `mapstructure:"period"`   // сколько хранить после удаления
+	Interval time.Duration `mapstructure:"interval"` // как часто запускать очистку
+}
+
+// WebhooksConfig - доставка событий внешним получателям
+type WebhooksConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	PollInterval   time.Duration `mapstructure:"poll_interval"`
+	BatchSize      int           `mapstructure:"batch_size"`
+	Concurrency    int           `mapstructure:"concurrency"`
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+	MaxAttempts    int           `mapstructure:"max_attempts"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+}
+
+// OutboxConfig - публикация событий из outbox
+type OutboxConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Publishers      []string      `mapstructure:"publishers" enum:"log,memory,nats,kafka"`
+	PollInterval    time.Duration `mapstructure:"poll_interval"`
+	BatchSize       int           `mapstructure:"batch_size"`
+	Lease           time.Duration `mapstructure:"lease"`
+	InitialBackoff  time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff      time.Duration `mapstructure:"max_backoff"`
+	Retention       time.Duration `mapstructure:"retention"`
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+	RenewalLead     time.Duration `mapstructure:"renewal_lead"`     // за сколько до продления записывать subscription.renewing
+	RenewalInterval time.Duration `mapstructure:"renewal_interval"` // как часто проверять продления
+	NATS            NATSConfig    `mapstructure:"nats"`
+	Kafka           KafkaConfig   `mapstructure:"kafka"`
+}
+
+// EventsConfig - поток изменений подписок (SSE)
+type EventsConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	ReplaySize   int           `mapstructure:"replay_size"`   // сколько последних событий хранить для Last-Event-ID
+	ClientBuffer int           `mapstructure:"client_buffer"` // очередь клиента, при переполнении клиент отключается
+	Heartbeat    time.Duration `mapstructure:"heartbeat"`
+}
+
+// AuthConfig - аутентификация по bearer-JWT
+type AuthConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Issuer         string        `mapstructure:"issuer"`   // пустой - не проверяется
+	Audience       string        `mapstructure:"audience"` // пустой - не проверяется
+	Algorithms     []string      `mapstructure:"algorithms" enum:"RS256,ES256,HS256"`
+	JWKSFile       string        `mapstructure:"jwks_file"`
+	JWKSURL        string        `mapstructure:"jwks_url"`
+	JWKSRefresh    time.Duration `mapstructure:"jwks_refresh"`
+	HMACSecret     string        `mapstructure:"hmac_secret" secret:"true"` // общий секрет для HS256
+	ClockSkew      time.Duration `mapstructure:"clock_skew"`
+	UserIDClaim    string        `mapstructure:"user_id_claim"` // claim с UUID пользователя - владельца подписок
+	TenantClaim    string        `mapstructure:"tenant_claim"`  // claim с организацией пользователя
+	RolesClaim     string        `mapstructure:"roles_claim"`
+	AdminRole      string        `mapstructure:"admin_role"`      // роль с доступом ко всем подпискам
+	APIKeys        bool          `mapstructure:"api_keys"`        // принимать API-ключи (Authorization: ApiKey)
+	AnonymousAdmin bool          `mapstructure:"anonymous_admin"` // только для разработки: без аутентификации любой клиент - администратор
+}
+
+// TenancyConfig - разделение данных организаций
+type TenancyConfig struct {
+	Header           string `mapstructure:"header"`             // организация запроса, если аутентификация выключена
+	RowLevelSecurity bool   `mapstructure:"row_level_security"` // передавать организацию в политики RLS Postgres
+}
+
+// RateLimitConfig - ограничение частоты запросов клиентов
+type RateLimitConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Store           string        `mapstructure:"store" enum:"memory,postgres"` // memory - на экземпляр, postgres - общий
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+	Read            LimitConfig   `mapstructure:"read"`
+	Write           LimitConfig   `mapstructure:"write"`
+	Reports         LimitConfig   `mapstructure:"reports"` // /cost и /export
+	IP              LimitConfig   `mapstructure:"ip"`      // все запросы с IP-адреса до аутентификации
+}
+
+// LimitConfig - корзина токенов: Burst запросов подряд, пополняется на Requests за Period
+type LimitConfig struct {
+	Requests int           `mapstructure:"requests"`
+	Period   time.Duration `mapstructure:"period"`
+	Burst    int           `mapstructure:"burst"`
+}
+
+// HealthConfig - проверки готовности
+type HealthConfig struct {
+	CheckTimeout time.Duration `mapstructure:"check_timeout"` // таймаут проверки одного компонента
+	DrainDelay   time.Duration `mapstructure:"drain_delay"`   // сколько отвечать not ready перед остановкой сервера
+}
+
+// MetricsConfig - метрики Prometheus
+type MetricsConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Path             string        `mapstructure:"path"`
+	Listen           string        `mapstructure:"listen"`            // отдельный адрес без аутентификации; пустой - основной порт, только администраторам
+	BusinessInterval time.Duration `mapstructure:"business_interval"` // как часто пересчитывать показатели подписок
+}
+
+// TracingConfig - трассировка OpenTelemetry
+type TracingConfig struct {
+	Exporter    string  `mapstructure:"exporter" enum:"otlp,stdout,none"`
+	Endpoint    string  `mapstructure:"endpoint"` // URL OTLP/HTTP коллектора
+	ServiceName string  `mapstructure:"service_name"`
+	SampleRatio float64 `mapstructure:"sample_ratio"` // доля трассируемых запросов без входящего traceparent
+}
+
+// CORSConfig - запросы из браузера с других источников
+type CORSConfig struct {
+	AllowOrigins []string `mapstructure:"allow_origins"` // "*" - любой источник
+}
+
+// minHMACSecretLen - минимальная длина секрета HS256 (RFC 7518, 3.2)
+const minHMACSecretLen = 32
+
+type NATSConfig struct {
+	URL           string `mapstructure:"url" secret:"true"` // может содержать логин и пароль
+	SubjectPrefix string `mapstructure:"subject_prefix"`
+}
+
+type KafkaConfig struct {
+	Brokers []string `mapstructure:"brokers"`
+	Topic   string   `mapstructure:"topic"`
 }
 
 func Load() (*Config, error) {
@@ -50,6 +244,11 @@ func Load() (*Config, error) {
 	// Явный биндинг для переменных окружения (для docker-compose)
 	bindEnvVariables()
 
+	return decode()
+}
+
+// decode собирает и проверяет конфигурацию из прочитанного viper файла и ENV
+func decode() (*Config, error) {
 	var cfg Config
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
@@ -57,9 +256,12 @@ func Load() (*Config, error) {
 
 	// Переопределяем из ENV (приоритет над YAML)
 	overrideFromEnv(&cfg)
-	
+	if err := loadSecretFiles(&cfg); err != nil {
+		return nil, err
+	}
+
 	if err := validate(&cfg); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+		return nil, err
 	}
 
 	return &cfg, nil
@@ -81,6 +283,9 @@ func bindEnvVariables() {
 	_ = viper.BindEnv("database.name", "APP_DATABASE_NAME")
 	_ = viper.BindEnv("server.port", "APP_SERVER_PORT")
 	_ = viper.BindEnv("server.host", "APP_SERVER_HOST")
+	_ = viper.BindEnv("auth.hmac_secret", "APP_AUTH_HMAC_SECRET")
+	_ = viper.BindEnv("database.auto_migrate", "APP_DATABASE_AUTO_MIGRATE")
+	_ = viper.BindEnv("database.replicas", "APP_DATABASE_REPLICAS")
 }
 
 func overrideFromEnv(cfg *Config) {
@@ -106,19 +311,165 @@ func overrideFromEnv(cfg *Config) {
 	if v := os.Getenv("APP_SERVER_HOST"); v != "" {
 		cfg.Server.Host = v
 	}
+	if v := os.Getenv("APP_AUTH_HMAC_SECRET"); v != "" {
+		cfg.Auth.HMACSecret = v
+	}
 }
 
+// validate проверяет конфигурацию целиком и возвращает *ValidationError
+// со всеми найденными ошибками
 func validate(cfg *Config) error {
+	var errs ValidationError
+	checkEnums(reflect.ValueOf(*cfg), "", &errs)
+
 	if cfg.Database.User == "" {
-		return fmt.Errorf("DB_USER is required")
+		errs.addf("database.user is required (APP_DATABASE_USER)")
 	}
 	if cfg.Database.Password == "" {
-		return fmt.Errorf("DB_PASSWORD is required")
+		errs.addf("database.password is required (APP_DATABASE_PASSWORD or APP_DATABASE_PASSWORD_FILE)")
 	}
 	if cfg.Database.Name == "" {
-		return fmt.Errorf("DB_NAME is required")
+		errs.addf("database.name is required (APP_DATABASE_NAME)")
+	}
+	checkPort(&errs, "database.port", cfg.Database.Port)
+	if d := cfg.Database; d.MaxConns < 0 || d.MinConns < 0 || (d.MaxConns > 0 && d.MinConns > d.MaxConns) {
+		errs.addf("database.min_conns must be between 0 and database.max_conns")
+	}
+	if d := cfg.Database; d.MaxConnLifetime < 0 || d.MaxConnIdleTime < 0 || d.HealthCheckPeriod < 0 || d.StatementTimeout < 0 {
+		errs.addf("database pool durations must not be negative")
+	}
+	if d := cfg.Database; d.ConnectAttempts < 1 || d.ConnectBackoff <= 0 || d.ConnectMaxBackoff < d.ConnectBackoff {
+		errs.addf("database.connect_attempts must be positive and connect_max_backoff not less than connect_backoff")
+	}
+	if d := cfg.Database; len(d.Replicas) > 0 {
+		if slices.ContainsFunc(d.Replicas, func(r string) bool { return strings.TrimSpace(r) == "" }) {
+			errs.addf("database.replicas must not contain empty entries")
+		}
+		if d.ReplicaMaxLag <= 0 || d.ReplicaCheckInterval <= 0 {
+			errs.addf("database.replica_max_lag and database.replica_check_interval must be positive")
+		}
+	}
+	if s := cfg.Logger.Sampling; s.Initial < 0 || s.Thereafter < 0 {
+		errs.addf("logger.sampling values must not be negative")
+	}
+	checkPort(&errs, "server.port", cfg.Server.Port)
+	if s := cfg.Server; s.ReadTimeout < 0 || s.ReadHeaderTimeout < 0 || s.WriteTimeout < 0 || s.IdleTimeout < 0 || s.MaxHeaderBytes < 0 {
+		errs.addf("server timeouts and max_header_bytes must not be negative")
+	}
+	if cfg.Server.ShutdownTimeout <= 0 {
+		errs.addf("server.shutdown_timeout must be positive")
 	}
-	return nil
+	if t := cfg.Server.TLS; (t.CertFile == "") != (t.KeyFile == "") {
+		errs.addf("server.tls.cert_file and server.tls.key_file must be set together")
+	}
+	if t := cfg.Server.TLS; t.ClientCAFile != "" && t.CertFile == "" {
+		errs.addf("server.tls.client_ca_file requires server.tls.cert_file")
+	}
+	if cfg.Health.CheckTimeout <= 0 || cfg.Health.DrainDelay < 0 {
+		errs.addf("health.check_timeout must be positive and health.drain_delay must not be negative")
+	}
+	if cfg.Retention.Enabled && (cfg.Retention.Period <= 0 || cfg.Retention.Interval <= 0) {
+		errs.addf("retention.period and retention.interval must be positive")
+	}
+	if w := cfg.Webhooks; w.Enabled {
+		if !cfg.Outbox.Enabled {
+			errs.addf("webhooks require outbox.enabled")
+		}
+		if w.PollInterval <= 0 || w.RequestTimeout <= 0 || w.InitialBackoff <= 0 || w.MaxBackoff <= 0 {
+			errs.addf("webhooks intervals and timeouts must be positive")
+		}
+		if w.BatchSize <= 0 || w.Concurrency <= 0 || w.MaxAttempts <= 0 {
+			errs.addf("webhooks.batch_size, webhooks.concurrency and webhooks.max_attempts must be positive")
+		}
+	}
+	if ev := cfg.Events; ev.Enabled {
+		if ev.ReplaySize < 0 || ev.ClientBuffer <= 0 || ev.Heartbeat <= 0 {
+			errs.addf("events.replay_size must not be negative, events.client_buffer and events.heartbeat must be positive")
+		}
+	}
+	if a := cfg.Auth; a.Enabled {
+		if len(a.Algorithms) == 0 {
+			errs.addf("auth.algorithms is required")
+		}
+		if a.JWKSFile != "" && a.JWKSURL != "" {
+			errs.addf("auth.jwks_file and auth.jwks_url are mutually exclusive")
+		}
+		if a.JWKSFile == "" && a.JWKSURL == "" && a.HMACSecret == "" {
+			errs.addf("auth requires jwks_file, jwks_url or hmac_secret")
+		}
+		if a.JWKSURL != "" && a.JWKSRefresh <= 0 {
+			errs.addf("auth.jwks_refresh must be positive")
+		}
+		if a.HMACSecret != "" && len(a.HMACSecret) < minHMACSecretLen {
+			errs.addf("auth.hmac_secret must be at least %d bytes", minHMACSecretLen)
+		}
+		if a.ClockSkew < 0 {
+			errs.addf("auth.clock_skew must not be negative")
+		}
+		if a.UserIDClaim == "" || a.TenantClaim == "" || a.RolesClaim == "" || a.AdminRole == "" {
+			errs.addf("auth.user_id_claim, auth.tenant_claim, auth.roles_claim and auth.admin_role are required")
+		}
+		if a.AnonymousAdmin {
+			errs.addf("auth.anonymous_admin requires auth.enabled: false")
+		}
+	}
+	if cfg.Tenancy.Header == "" {
+		errs.addf("tenancy.header is required")
+	}
+	if m := cfg.Metrics; m.Enabled {
+		if !strings.HasPrefix(m.Path, "/") {
+			errs.addf("metrics.path must start with /")
+		}
+		if m.BusinessInterval <= 0 {
+			errs.addf("metrics.business_interval must be positive")
+		}
+		if m.Listen != "" {
+			if _, port, err := net.SplitHostPort(m.Listen); err != nil {
+				errs.addf("metrics.listen must be host:port, got %q", m.Listen)
+			} else {
+				checkPort(&errs, "metrics.listen", port)
+			}
+		}
+	}
+	if t := cfg.Tracing; t.Exporter != "none" {
+		if t.ServiceName == "" {
+			errs.addf("tracing.service_name is required")
+		}
+		if t.SampleRatio < 0 || t.SampleRatio > 1 {
+			errs.addf("tracing.sample_ratio must be between 0 and 1")
+		}
+	}
+	// хранилище создается при старте и выключенным лимитам, чтобы их можно
+	// было включить без перезапуска
+	if cfg.RateLimit.CleanupInterval <= 0 {
+		errs.addf("rate_limit.cleanup_interval must be positive")
+	}
+	if rl := cfg.RateLimit; rl.Enabled {
+		for _, l := range []struct {
+			name string
+			LimitConfig
+		}{{"read", rl.Read}, {"write", rl.Write}, {"reports", rl.Reports}, {"ip", rl.IP}} {
+			if l.Requests <= 0 || l.Period <= 0 || l.Burst <= 0 {
+				errs.addf("rate_limit.%s requests, period and burst must be positive", l.name)
+			}
+		}
+	}
+	if o := cfg.Outbox; o.Enabled {
+		if o.PollInterval <= 0 || o.Lease <= 0 || o.InitialBackoff <= 0 || o.MaxBackoff <= 0 ||
+			o.Retention <= 0 || o.CleanupInterval <= 0 || o.RenewalInterval <= 0 {
+			errs.addf("outbox intervals must be positive")
+		}
+		if o.BatchSize <= 0 {
+			errs.addf("outbox.batch_size must be positive")
+		}
+		if slices.Contains(o.Publishers, "nats") && (o.NATS.URL == "" || o.NATS.SubjectPrefix == "") {
+			errs.addf("outbox.nats.url and outbox.nats.subject_prefix are required")
+		}
+		if slices.Contains(o.Publishers, "kafka") && (len(o.Kafka.Brokers) == 0 || o.Kafka.Topic == "") {
+			errs.addf("outbox.kafka.brokers and outbox.kafka.topic are required")
+		}
+	}
+	return errs.err()
 }
 
 // GetDSN возвращает connection string для PostgreSQL
@@ -128,3 +479,13 @@ func (c *DatabaseConfig) GetDSN() string {
 		c.Host, c.Port, c.User, c.Password, c.Name, c.SSLMode,
 	)
 }
+
+// ReplicaDSNs возвращает connection string реплик. Параметры реплики
+// указываются после параметров основной БД и заменяют их
+func (c *DatabaseConfig) ReplicaDSNs() []string {
+	dsns := make([]string, 0, len(c.Replicas))
+	for _, r := range c.Replicas {
+		dsns = append(dsns, c.GetDSN()+" "+r)
+	}
+	return dsns
+}
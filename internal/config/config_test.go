@@ -0,0 +1,136 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testConfig возвращает проверенную конфигурацию из config.yaml репозитория
+func testConfig(t *testing.T) *Config {
+	t.Helper()
+	t.Setenv("CONFIG_PATH", filepath.Join("..", "..", "config.yaml"))
+	t.Setenv("APP_DATABASE_USER", "app")
+	t.Setenv("APP_DATABASE_PASSWORD", "secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(cfg *Config)
+		want   []string // подстроки ожидаемых ошибок, пустой - конфигурация верна
+	}{
+		{name: "repository config.yaml", change: func(*Config) {}},
+		{
+			name:   "missing credentials",
+			change: func(cfg *Config) { cfg.Database.User, cfg.Database.Password = "", "" },
+			want:   []string{"database.user is required", "database.password is required"},
+		},
+		{
+			name:   "unknown sslmode",
+			change: func(cfg *Config) { cfg.Database.SSLMode = "sometimes" },
+			want:   []string{`unknown database.sslmode "sometimes"`},
+		},
+		{
+			name:   "unknown publisher in list",
+			change: func(cfg *Config) { cfg.Outbox.Publishers = []string{"log", "pigeon"} },
+			want:   []string{`unknown outbox.publishers "pigeon"`},
+		},
+		{
+			name:   "server port out of range",
+			change: func(cfg *Config) { cfg.Server.Port = "70000" },
+			want:   []string{"server.port must be a port number"},
+		},
+		{
+			name: "metrics listen without port",
+			change: func(cfg *Config) {
+				cfg.Metrics.Enabled = true
+				cfg.Metrics.Listen = "localhost"
+			},
+			want: []string{"metrics.listen must be host:port"},
+		},
+		{
+			name: "enabled rate limit with empty bucket",
+			change: func(cfg *Config) {
+				cfg.RateLimit.Enabled = true
+				cfg.RateLimit.IP = LimitConfig{}
+			},
+			want: []string{"rate_limit.ip requests, period and burst must be positive"},
+		},
+		{
+			name: "disabled rate limit is not checked",
+			change: func(cfg *Config) {
+				cfg.RateLimit.Enabled = false
+				cfg.RateLimit.Read = LimitConfig{}
+			},
+		},
+		{
+			name: "anonymous admin with enabled auth",
+			change: func(cfg *Config) {
+				cfg.Auth.Enabled = true
+				cfg.Auth.HMACSecret = strings.Repeat("k", minHMACSecretLen)
+				cfg.Auth.AnonymousAdmin = true
+			},
+			want: []string{"auth.anonymous_admin requires auth.enabled: false"},
+		},
+		{
+			name:   "cleanup interval is checked even with disabled rate limit",
+			change: func(cfg *Config) { cfg.RateLimit.CleanupInterval = 0 },
+			want:   []string{"rate_limit.cleanup_interval must be positive"},
+		},
+		{
+			name: "replicas with negative lag",
+			change: func(cfg *Config) {
+				cfg.Database.Replicas = []string{"host=replica", " "}
+				cfg.Database.ReplicaMaxLag = -time.Second
+			},
+			want: []string{"database.replicas must not contain empty entries", "database.replica_max_lag and database.replica_check_interval must be positive"},
+		},
+		{
+			name: "all problems are reported at once",
+			change: func(cfg *Config) {
+				cfg.Database.User = ""
+				cfg.Logger.Level = "verbose"
+				cfg.Server.Port = "http"
+			},
+			want: []string{"database.user is required", `unknown logger.level "verbose"`, "server.port must be a port number"},
+		},
+	}
+
+	base := testConfig(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *base
+			tt.change(&cfg)
+
+			err := validate(&cfg)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("validate() error = %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("validate() error = %v, want *ValidationError", err)
+			}
+			if len(verr.Problems) != len(tt.want) {
+				t.Errorf("got %d problems, want %d:\n%v", len(verr.Problems), len(tt.want), err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("validate() error does not mention %q:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
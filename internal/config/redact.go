@@ -0,0 +1,46 @@
+package config
+
+import (
+	"reflect"
+	"time"
+)
+
+// redactedValue заменяет значения полей с тегом secret:"true"
+const redactedValue = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Redacted возвращает конфигурацию в виде дерева с ключами config.yaml.
+// Непустые значения секретов (поля с тегом secret:"true") скрыты
+func Redacted(cfg *Config) map[string]interface{} {
+	return tree(reflect.ValueOf(*cfg), true)
+}
+
+// tree возвращает структуру в виде дерева с ключами из тегов mapstructure.
+// При redact непустые секреты заменяются на redactedValue
+func tree(v reflect.Value, redact bool) map[string]interface{} {
+	out := make(map[string]interface{}, v.NumField())
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" || !field.IsExported() {
+			continue
+		}
+		out[key] = treeValue(v.Field(i), redact && field.Tag.Get("secret") == "true", redact)
+	}
+	return out
+}
+
+func treeValue(v reflect.Value, secret, redact bool) interface{} {
+	switch {
+	case secret && !v.IsZero() && (v.Kind() != reflect.Slice || v.Len() > 0):
+		return redactedValue
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Struct:
+		return tree(v, redact)
+	default:
+		return v.Interface()
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// reloadable - настройки, которые сервис применяет без перезапуска.
+// Остальные изменения вступают в силу только после перезапуска
+var reloadable = []string{
+	"logger.level",
+	"rate_limit.enabled",
+	"rate_limit.read",
+	"rate_limit.write",
+	"rate_limit.reports",
+	"rate_limit.ip",
+	"cors.allow_origins",
+}
+
+// Change - изменение одной настройки. Key - путь в config.yaml, значения
+// секретов скрыты
+type Change struct {
+	Key string      `json:"key"`
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Diff возвращает изменения настроек от old к cfg, разделенные на
+// применимые без перезапуска (reload) и требующие перезапуска (restart)
+func Diff(old, cfg *Config) (reload, restart []Change) {
+	before, after := flatten(tree(reflect.ValueOf(*old), false)), flatten(tree(reflect.ValueOf(*cfg), false))
+	shownBefore, shownAfter := flatten(Redacted(old)), flatten(Redacted(cfg))
+
+	keys := make([]string, 0, len(after))
+	for key := range after {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if reflect.DeepEqual(before[key], after[key]) {
+			continue
+		}
+		change := Change{Key: key, Old: shownBefore[key], New: shownAfter[key]}
+		if isReloadable(key) {
+			reload = append(reload, change)
+		} else {
+			restart = append(restart, change)
+		}
+	}
+	return reload, restart
+}
+
+func isReloadable(key string) bool {
+	for _, k := range reloadable {
+		if key == k || strings.HasPrefix(key, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// flatten превращает дерево настроек в пары путь - значение
+func flatten(t map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	var walk func(prefix string, t map[string]interface{})
+	walk = func(prefix string, t map[string]interface{}) {
+		for key, v := range t {
+			if sub, ok := v.(map[string]interface{}); ok {
+				walk(prefix+key+".", sub)
+				continue
+			}
+			out[prefix+key] = v
+		}
+	}
+	walk("", t)
+	return out
+}
+
+// Watch следит за файлом конфигурации, прочитанным Load, и при каждом его
+// изменении передает в fn новую проверенную конфигурацию или ошибку ее
+// чтения. fn вызывается из отдельной горутины
+func Watch(fn func(*Config, error)) error {
+	path := viper.ConfigFileUsed()
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to watch config file: %w", err)
+	}
+
+	// viper перечитывает файл сам перед вызовом OnConfigChange. Файл, который
+	// не удалось разобрать, не меняет прочитанные настройки, а ошибка только
+	// пишется в лог viper, поэтому лог перенаправляется в fn
+	viper.SetOptions(viper.WithLogger(slog.New(watchErrors{fn: fn})))
+	viper.OnConfigChange(func(fsnotify.Event) {
+		fn(decode())
+	})
+	viper.WatchConfig()
+	return nil
+}
+
+// watchErrors - обработчик лога viper, передающий ошибки в обработчик Watch
+type watchErrors struct {
+	fn func(*Config, error)
+}
+
+func (h watchErrors) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelError
+}
+
+func (h watchErrors) Handle(_ context.Context, r slog.Record) error {
+	h.fn(nil, errors.New(r.Message))
+	return nil
+}
+
+func (h watchErrors) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h watchErrors) WithGroup(string) slog.Handler      { return h }
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		change      func(cfg *Config)
+		wantReload  []Change
+		wantRestart []Change
+	}{
+		{name: "no changes", change: func(*Config) {}},
+		{
+			name:       "log level",
+			change:     func(cfg *Config) { cfg.Logger.Level = "debug" },
+			wantReload: []Change{{Key: "logger.level", Old: "info", New: "debug"}},
+		},
+		{
+			name: "rate limits",
+			change: func(cfg *Config) {
+				cfg.RateLimit.Enabled = true
+				cfg.RateLimit.Write.Burst = 5
+			},
+			wantReload: []Change{
+				{Key: "rate_limit.enabled", Old: false, New: true},
+				{Key: "rate_limit.write.burst", Old: 20, New: 5},
+			},
+		},
+		{
+			name:       "cors origins",
+			change:     func(cfg *Config) { cfg.CORS.AllowOrigins = []string{"https://app.example.com"} },
+			wantReload: []Change{{Key: "cors.allow_origins", Old: []string{"*"}, New: []string{"https://app.example.com"}}},
+		},
+		{
+			name:        "server settings require restart",
+			change:      func(cfg *Config) { cfg.Server.Port = "9000" },
+			wantRestart: []Change{{Key: "server.port", Old: "8081", New: "9000"}},
+		},
+		{
+			name:        "durations are shown as strings",
+			change:      func(cfg *Config) { cfg.Server.ShutdownTimeout = time.Minute },
+			wantRestart: []Change{{Key: "server.shutdown_timeout", Old: "20s", New: "1m0s"}},
+		},
+		{
+			name:        "secrets are redacted",
+			change:      func(cfg *Config) { cfg.Database.Password = "rotated" },
+			wantRestart: []Change{{Key: "database.password", Old: redactedValue, New: redactedValue}},
+		},
+		{
+			name:        "replica dsns are redacted",
+			change:      func(cfg *Config) { cfg.Database.Replicas = []string{"host=replica password=p"} },
+			wantRestart: []Change{{Key: "database.replicas", Old: []string{}, New: redactedValue}},
+		},
+		{
+			name: "reload and restart together",
+			change: func(cfg *Config) {
+				cfg.Logger.Level = "warn"
+				cfg.Logger.Format = "console"
+			},
+			wantReload:  []Change{{Key: "logger.level", Old: "info", New: "warn"}},
+			wantRestart: []Change{{Key: "logger.format", Old: "json", New: "console"}},
+		},
+	}
+
+	base := testConfig(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *base
+			tt.change(&cfg)
+
+			reload, restart := Diff(base, &cfg)
+			if !reflect.DeepEqual(reload, tt.wantReload) {
+				t.Errorf("reload = %#v, want %#v", reload, tt.wantReload)
+			}
+			if !reflect.DeepEqual(restart, tt.wantRestart) {
+				t.Errorf("restart = %#v, want %#v", restart, tt.wantRestart)
+			}
+		})
+	}
+}
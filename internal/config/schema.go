@@ -0,0 +1,55 @@
+package config
+
+import (
+	"reflect"
+)
+
+// durationPattern - длительность в формате time.ParseDuration, например "1m30s"
+const durationPattern = `^(0|-?([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+)$`
+
+// Schema возвращает JSON Schema файла config.yaml, построенную по структуре
+// Config: типы полей, допустимые значения из тегов enum и формат длительностей.
+// Неизвестные ключи считаются ошибкой, чтобы редактор подсвечивал опечатки
+func Schema() map[string]interface{} {
+	schema := schemaOf(reflect.TypeOf(Config{}), reflect.StructField{})
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "subscription-service config.yaml"
+	return schema
+}
+
+func schemaOf(t reflect.Type, field reflect.StructField) map[string]interface{} {
+	if t == durationType {
+		return map[string]interface{}{"type": "string", "pattern": durationPattern}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		props := make(map[string]interface{}, t.NumField())
+		for i := range t.NumField() {
+			f := t.Field(i)
+			key := f.Tag.Get("mapstructure")
+			if key == "" || !f.IsExported() {
+				continue
+			}
+			props[key] = schemaOf(f.Type, f)
+		}
+		return map[string]interface{}{"type": "object", "properties": props, "additionalProperties": false}
+	case reflect.Slice:
+		// элементы списка наследуют enum поля
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), field)}
+	case reflect.String:
+		s := map[string]interface{}{"type": "string"}
+		if values := enumValues(field); values != nil {
+			s["enum"] = values
+		}
+		return s
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{}
+	}
+}
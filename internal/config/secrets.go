@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// loadSecretFiles читает секреты (поля с тегом secret:"true") из файлов,
+// указанных в переменных APP_<КЛЮЧ>_FILE, например
+// APP_DATABASE_PASSWORD_FILE=/run/secrets/db_password. Так секреты Docker и
+// Kubernetes не попадают в переменные окружения процесса. Значение из файла
+// заменяет значение из config.yaml, конечный перевод строки отбрасывается
+func loadSecretFiles(cfg *Config) error {
+	return secretFiles(reflect.ValueOf(cfg).Elem(), "APP")
+}
+
+func secretFiles(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" || !field.IsExported() {
+			continue
+		}
+		env := prefix + "_" + strings.ToUpper(key)
+
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			if err := secretFiles(v.Field(i), env); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.Tag.Get("secret") != "true" || field.Type.Kind() != reflect.String {
+			continue
+		}
+
+		path := os.Getenv(env + "_FILE")
+		if path == "" {
+			continue
+		}
+		if os.Getenv(env) != "" {
+			return fmt.Errorf("%s and %s_FILE are mutually exclusive", env, env)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s_FILE: %w", env, err)
+		}
+		v.Field(i).SetString(strings.TrimRight(string(data), "\r\n"))
+	}
+	return nil
+}
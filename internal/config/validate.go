@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ValidationError перечисляет все ошибки конфигурации, а не только первую
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "config validation failed:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func (e *ValidationError) addf(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// err возвращает nil, если ошибок нет
+func (e *ValidationError) err() error {
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return e
+}
+
+// enumValues возвращает допустимые значения поля из тега enum
+func enumValues(field reflect.StructField) []string {
+	tag := field.Tag.Get("enum")
+	if tag == "" {
+		return nil
+	}
+	return strings.Split(tag, ",")
+}
+
+// checkEnums проверяет строки и списки строк с тегом enum во всех вложенных
+// структурах. prefix - путь структуры в config.yaml
+func checkEnums(v reflect.Value, prefix string, errs *ValidationError) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			checkEnums(v.Field(i), key+".", errs)
+			continue
+		}
+		allowed := enumValues(field)
+		if allowed == nil {
+			continue
+		}
+
+		var values []string
+		switch f := v.Field(i); f.Kind() {
+		case reflect.String:
+			values = []string{f.String()}
+		case reflect.Slice:
+			values = f.Interface().([]string)
+		}
+		for _, value := range values {
+			if !slices.Contains(allowed, value) {
+				errs.addf("unknown %s %q, expected one of: %s", key, value, strings.Join(allowed, ", "))
+			}
+		}
+	}
+}
+
+// checkPort проверяет номер TCP-порта
+func checkPort(errs *ValidationError, key, port string) {
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		errs.addf("%s must be a port number between 1 and 65535, got %q", key, port)
+	}
+}
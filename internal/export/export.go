@@ -0,0 +1,242 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/xuri/excelize/v2"
+)
+
+// Format - формат выгрузки подписок
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatXLSX   Format = "xlsx"
+)
+
+// MonthLayout - формат дат начала и окончания подписки (как во входных DTO)
+const MonthLayout = "01-2006"
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Columns - порядок колонок в табличных форматах
+var Columns = []string{
+	"id",
+	"tenant_id",
+	"service_name",
+	"price",
+	"user_id",
+	"start_date",
+	"end_date",
+	"created_at",
+	"updated_at",
+	"deleted_at",
+}
+
+// priceColumn - индекс цены в Columns
+const priceColumn = 3
+
+// ParseFormat проверяет и возвращает формат выгрузки
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatCSV, FormatNDJSON, FormatXLSX:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+	}
+}
+
+// ContentType возвращает MIME-тип формата
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Writer последовательно записывает подписки в выходной поток
+type Writer interface {
+	Write(sub *models.Subscription) error
+	// Flush сбрасывает буферизованные строки в поток (если формат это позволяет)
+	Flush() error
+	// Close дописывает хвост файла; после Close писать нельзя
+	Close() error
+}
+
+// NewWriter создает Writer для указанного формата
+func NewWriter(f Format, w io.Writer) (Writer, error) {
+	switch f {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+	}
+}
+
+// record возвращает значения колонок подписки в порядке Columns
+func record(sub *models.Subscription) []string {
+	endDate := ""
+	if sub.EndDate != nil {
+		endDate = sub.EndDate.Format(MonthLayout)
+	}
+	deletedAt := ""
+	if sub.DeletedAt != nil {
+		deletedAt = sub.DeletedAt.Format(time.RFC3339)
+	}
+	return []string{
+		sub.ID.String(),
+		escapeCell(sub.TenantID),
+		escapeCell(sub.ServiceName),
+		strconv.Itoa(sub.Price),
+		sub.UserID.String(),
+		sub.StartDate.Format(MonthLayout),
+		endDate,
+		sub.CreatedAt.Format(time.RFC3339),
+		sub.UpdatedAt.Format(time.RFC3339),
+		deletedAt,
+	}
+}
+
+// escapeCell защищает от выполнения пользовательского текста как формулы
+// в табличном редакторе: значение, начинающееся с символа формулы или
+// апострофа, получает префикс-апостроф, который редактор не показывает
+func escapeCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@'\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// unescapeCell снимает префикс, добавленный escapeCell
+func unescapeCell(s string) string {
+	return strings.TrimPrefix(s, "'")
+}
+
+// CSV
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Columns); err != nil {
+		return nil, fmt.Errorf("failed to write csv header: %w", err)
+	}
+	return &csvWriter{w: cw}, nil
+}
+
+func (c *csvWriter) Write(sub *models.Subscription) error {
+	return c.w.Write(record(sub))
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	return c.Flush()
+}
+
+// NDJSON
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(sub *models.Subscription) error {
+	return n.enc.Encode(sub)
+}
+
+func (n *ndjsonWriter) Flush() error { return nil }
+
+func (n *ndjsonWriter) Close() error { return nil }
+
+// XLSX
+
+// xlsxWriter пишет строки через потоковый writer excelize (строки сверх
+// порога уходят во временный файл), но итоговый архив формируется только в Close
+type xlsxWriter struct {
+	out  io.Writer
+	file *excelize.File
+	sw   *excelize.StreamWriter
+	row  int
+}
+
+const xlsxSheet = "Sheet1"
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	f := excelize.NewFile()
+	sw, err := f.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to create xlsx stream: %w", err)
+	}
+
+	x := &xlsxWriter{out: w, file: f, sw: sw, row: 1}
+	header := make([]interface{}, len(Columns))
+	for i, col := range Columns {
+		header[i] = col
+	}
+	if err := x.setRow(header); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxWriter) setRow(values []interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	if err := x.sw.SetRow(cell, values); err != nil {
+		return fmt.Errorf("failed to write xlsx row: %w", err)
+	}
+	x.row++
+	return nil
+}
+
+func (x *xlsxWriter) Write(sub *models.Subscription) error {
+	rec := record(sub)
+	values := make([]interface{}, len(rec))
+	for i, v := range rec {
+		values[i] = v
+	}
+	// цена - число, чтобы в таблице работали формулы
+	values[priceColumn] = sub.Price
+	return x.setRow(values)
+}
+
+func (x *xlsxWriter) Flush() error { return nil }
+
+func (x *xlsxWriter) Close() error {
+	defer x.file.Close()
+	if err := x.sw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush xlsx stream: %w", err)
+	}
+	if _, err := x.file.WriteTo(x.out); err != nil {
+		return fmt.Errorf("failed to write xlsx: %w", err)
+	}
+	return nil
+}
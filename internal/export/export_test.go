@@ -0,0 +1,211 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/xuri/excelize/v2"
+)
+
+func testSubscriptions() []*models.Subscription {
+	created := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	deleted := time.Date(2025, 3, 5, 8, 30, 0, 0, time.UTC)
+	return []*models.Subscription{
+		{
+			ID:          uuid.MustParse("0b7e3a7c-7c1f-4a53-9f7c-1d2e3f4a5b6c"),
+			TenantID:    "acme",
+			ServiceName: "Yandex Plus, семейная",
+			Price:       400,
+			UserID:      uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"),
+			StartDate:   time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:     &end,
+			CreatedAt:   created,
+			UpdatedAt:   created,
+		},
+		{
+			ID:          uuid.MustParse("4f2c8d9e-1a2b-4c3d-8e9f-0a1b2c3d4e5f"),
+			TenantID:    "acme",
+			ServiceName: `=HYPERLINK("http://evil","Netflix")`,
+			Price:       799,
+			UserID:      uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"),
+			StartDate:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			CreatedAt:   created,
+			UpdatedAt:   deleted,
+			DeletedAt:   &deleted,
+		},
+	}
+}
+
+func writeAll(t *testing.T, f Format, subs []*models.Subscription) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(f, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range subs {
+		if err := w.Write(sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, f := range []Format{FormatCSV, FormatNDJSON} {
+		t.Run(string(f), func(t *testing.T) {
+			want := testSubscriptions()
+			r, err := NewReader(f, bytes.NewReader(writeAll(t, f, want)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []*models.Subscription
+			for {
+				sub, err := r.Read()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, sub)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("read back %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCSVColumns(t *testing.T) {
+	out := string(writeAll(t, FormatCSV, testSubscriptions()))
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want header and 2 rows:\n%s", len(lines), out)
+	}
+
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{name: "header", line: lines[0], want: "id,tenant_id,service_name,price,user_id,start_date,end_date,created_at,updated_at,deleted_at"},
+		{name: "live row", line: lines[1], want: `0b7e3a7c-7c1f-4a53-9f7c-1d2e3f4a5b6c,acme,"Yandex Plus, семейная",400,60601fee-2bf1-4721-ae6f-7636e79a0cba,07-2025,12-2025,2025-01-10T12:00:00Z,2025-01-10T12:00:00Z,`},
+		{name: "deleted row with formula", line: lines[2], want: `4f2c8d9e-1a2b-4c3d-8e9f-0a1b2c3d4e5f,acme,"'=HYPERLINK(""http://evil"",""Netflix"")",799,60601fee-2bf1-4721-ae6f-7636e79a0cba,01-2025,,2025-01-10T12:00:00Z,2025-03-05T08:30:00Z,2025-03-05T08:30:00Z`},
+	}
+	for _, tt := range tests {
+		if tt.line != tt.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tt.name, tt.line, tt.want)
+		}
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	f, err := excelize.OpenReader(bytes.NewReader(writeAll(t, FormatXLSX, testSubscriptions())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows(xlsxSheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want header and 2 rows", len(rows))
+	}
+	if !reflect.DeepEqual(rows[0], Columns) {
+		t.Errorf("header = %v, want %v", rows[0], Columns)
+	}
+
+	tests := []struct {
+		cell string
+		want string
+	}{
+		{cell: "B2", want: "acme"},
+		{cell: "C2", want: "Yandex Plus, семейная"},
+		{cell: "D2", want: "400"},
+		{cell: "J2", want: ""},
+		{cell: "C3", want: `'=HYPERLINK("http://evil","Netflix")`},
+		{cell: "J3", want: "2025-03-05T08:30:00Z"},
+	}
+	for _, tt := range tests {
+		got, err := f.GetCellValue(xlsxSheet, tt.cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.cell, got, tt.want)
+		}
+	}
+
+	// цена записана числом, а не строкой
+	if typ, err := f.GetCellType(xlsxSheet, "D2"); err != nil || typ == excelize.CellTypeSharedString || typ == excelize.CellTypeInlineString {
+		t.Errorf("price cell type = %v, err = %v, want number", typ, err)
+	}
+	if formula, _ := f.GetCellFormula(xlsxSheet, "C3"); formula != "" {
+		t.Errorf("C3 contains formula %q", formula)
+	}
+}
+
+func TestEscapeCell(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: ""},
+		{in: "Netflix", want: "Netflix"},
+		{in: "=1+1", want: "'=1+1"},
+		{in: "+7 999", want: "'+7 999"},
+		{in: "-cmd", want: "'-cmd"},
+		{in: "@SUM(A1)", want: "'@SUM(A1)"},
+		{in: "\t=1", want: "'\t=1"},
+		{in: "'quoted", want: "''quoted"},
+		{in: "a=b", want: "a=b"},
+	}
+	for _, tt := range tests {
+		got := escapeCell(tt.in)
+		if got != tt.want {
+			t.Errorf("escapeCell(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if back := unescapeCell(got); back != tt.in {
+			t.Errorf("unescapeCell(%q) = %q, want %q", got, back, tt.in)
+		}
+	}
+}
+
+func TestCSVReaderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "missing column", input: "service_name,price,user_id\n", wantErr: `csv header has no "start_date" column`},
+		{name: "invalid price", input: "service_name,price,user_id,start_date\nNetflix,free,60601fee-2bf1-4721-ae6f-7636e79a0cba,07-2025\n", wantErr: "line 2: invalid price"},
+		{name: "end before start", input: "service_name,price,user_id,start_date,end_date\nNetflix,400,60601fee-2bf1-4721-ae6f-7636e79a0cba,07-2025,01-2025\n", wantErr: "line 2: end_date is before start_date"},
+		{name: "invalid deleted_at", input: "service_name,price,user_id,start_date,deleted_at\nNetflix,400,60601fee-2bf1-4721-ae6f-7636e79a0cba,07-2025,yesterday\n", wantErr: "line 2: invalid deleted_at"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(FormatCSV, strings.NewReader(tt.input))
+			if err == nil {
+				_, err = r.Read()
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
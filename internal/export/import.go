@@ -0,0 +1,178 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/untibullet/subscription-service-em/internal/models"
+)
+
+// requiredColumns - колонки, без которых подписку не восстановить
+var requiredColumns = []string{"service_name", "price", "user_id", "start_date"}
+
+// Reader последовательно читает и проверяет подписки из входного потока.
+// После последней подписки Read возвращает io.EOF. Незаполненные id,
+// tenant_id, created_at, updated_at и deleted_at остаются нулевыми
+type Reader interface {
+	Read() (*models.Subscription, error)
+}
+
+// NewReader создает Reader для указанного формата. XLSX читать нельзя
+func NewReader(f Format, r io.Reader) (Reader, error) {
+	switch f {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		return &ndjsonReader{dec: json.NewDecoder(r)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q cannot be imported", ErrUnknownFormat, f)
+	}
+}
+
+// validate проверяет прочитанную подписку по тем же правилам, что и API
+func validate(sub *models.Subscription) error {
+	switch {
+	case sub.ServiceName == "" || len(sub.ServiceName) > 255:
+		return errors.New("invalid service_name")
+	case sub.Price <= 0:
+		return errors.New("invalid price")
+	case sub.UserID == uuid.Nil:
+		return errors.New("user_id is required")
+	case sub.StartDate.IsZero():
+		return errors.New("start_date is required")
+	case sub.EndDate != nil && sub.EndDate.Before(sub.StartDate):
+		return errors.New("end_date is before start_date")
+	}
+	return nil
+}
+
+// CSV
+
+type csvReader struct {
+	r    *csv.Reader
+	cols map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[name] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("csv header has no %q column", name)
+		}
+	}
+	return &csvReader{r: cr, cols: cols}, nil
+}
+
+func (c *csvReader) Read() (*models.Subscription, error) {
+	rec, err := c.r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+	line, _ := c.r.FieldPos(0)
+
+	sub, err := c.parse(rec)
+	if err == nil {
+		err = validate(sub)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", line, err)
+	}
+	return sub, nil
+}
+
+// field возвращает значение колонки или пустую строку, если колонки нет
+func (c *csvReader) field(rec []string, name string) string {
+	if i, ok := c.cols[name]; ok && i < len(rec) {
+		return rec[i]
+	}
+	return ""
+}
+
+func (c *csvReader) parse(rec []string) (*models.Subscription, error) {
+	sub := models.Subscription{
+		TenantID:    unescapeCell(c.field(rec, "tenant_id")),
+		ServiceName: unescapeCell(c.field(rec, "service_name")),
+	}
+
+	var err error
+	if v := c.field(rec, "id"); v != "" {
+		if sub.ID, err = uuid.Parse(v); err != nil {
+			return nil, fmt.Errorf("invalid id: %w", err)
+		}
+	}
+	if sub.Price, err = strconv.Atoi(c.field(rec, "price")); err != nil {
+		return nil, fmt.Errorf("invalid price: %w", err)
+	}
+	if sub.UserID, err = uuid.Parse(c.field(rec, "user_id")); err != nil {
+		return nil, fmt.Errorf("invalid user_id: %w", err)
+	}
+	if sub.StartDate, err = time.Parse(MonthLayout, c.field(rec, "start_date")); err != nil {
+		return nil, fmt.Errorf("invalid start_date: %w", err)
+	}
+	if v := c.field(rec, "end_date"); v != "" {
+		end, err := time.Parse(MonthLayout, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end_date: %w", err)
+		}
+		sub.EndDate = &end
+	}
+	if v := c.field(rec, "created_at"); v != "" {
+		if sub.CreatedAt, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, fmt.Errorf("invalid created_at: %w", err)
+		}
+	}
+	if v := c.field(rec, "updated_at"); v != "" {
+		if sub.UpdatedAt, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, fmt.Errorf("invalid updated_at: %w", err)
+		}
+	}
+	if v := c.field(rec, "deleted_at"); v != "" {
+		deletedAt, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deleted_at: %w", err)
+		}
+		sub.DeletedAt = &deletedAt
+	}
+	return &sub, nil
+}
+
+// NDJSON
+
+type ndjsonReader struct {
+	dec    *json.Decoder
+	record int
+}
+
+func (n *ndjsonReader) Read() (*models.Subscription, error) {
+	n.record++
+	var sub models.Subscription
+	if err := n.dec.Decode(&sub); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("record %d: invalid json: %w", n.record, err)
+	}
+	if err := validate(&sub); err != nil {
+		return nil, fmt.Errorf("record %d: %w", n.record, err)
+	}
+	return &sub, nil
+}
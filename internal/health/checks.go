@@ -0,0 +1,28 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/untibullet/subscription-service-em/internal/repository"
+)
+
+// Database проверяет соединение с БД
+func Database(repo repository.HealthRepository) CheckFunc {
+	return repo.Ping
+}
+
+// Migrations проверяет, что схема БД обновлена хотя бы до версии expected.
+// Более новая схема допустима: ее могли применить для следующей версии сервиса
+func Migrations(repo repository.HealthRepository, expected int64) CheckFunc {
+	return func(ctx context.Context) error {
+		version, err := repo.SchemaVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if version < expected {
+			return fmt.Errorf("schema version %d, expected %d", version, expected)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MissedRuns - сколько плановых запусков фоновая задача может пропустить,
+// прежде чем считаться неисправной
+const MissedRuns = 3
+
+const (
+	StatusOK       = "ok"
+	StatusFailing  = "failing"
+	StatusDraining = "draining"
+)
+
+// CheckFunc проверяет компонент сервиса. nil - компонент исправен
+type CheckFunc func(ctx context.Context) error
+
+// Component - результат проверки компонента
+type Component struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report - результат проверки готовности
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// Ready сообщает, готов ли сервис принимать запросы
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker проверяет готовность сервиса по зарегистрированным компонентам
+type Checker struct {
+	timeout  time.Duration
+	checks   []check
+	draining atomic.Bool
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add регистрирует проверку компонента. Вызывается до запуска сервера
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// Drain переводит сервис в неготовность перед остановкой, чтобы балансировщик
+// перестал направлять на него запросы
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Check параллельно проверяет все компоненты, ограничивая каждую проверку timeout
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Components: make(map[string]Component, len(c.checks))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, ch := range c.checks {
+		wg.Go(func() {
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			comp := Component{Status: StatusOK}
+			if err := ch.fn(checkCtx); err != nil {
+				comp = Component{Status: StatusFailing, Error: err.Error()}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[ch.name] = comp
+			if comp.Status != StatusOK {
+				report.Status = StatusFailing
+			}
+		})
+	}
+	wg.Wait()
+
+	if c.draining.Load() {
+		report.Status = StatusDraining
+	}
+	return report
+}
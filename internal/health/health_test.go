@@ -0,0 +1,107 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckerCheck(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connection refused") }
+	// зависшая проверка завершается по таймауту Checker
+	hanging := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name     string
+		checks   map[string]CheckFunc
+		draining bool
+		want     Report
+	}{
+		{
+			name: "no checks",
+			want: Report{Status: StatusOK, Components: map[string]Component{}},
+		},
+		{
+			name:   "all ok",
+			checks: map[string]CheckFunc{"database": ok, "relay": ok},
+			want: Report{Status: StatusOK, Components: map[string]Component{
+				"database": {Status: StatusOK},
+				"relay":    {Status: StatusOK},
+			}},
+		},
+		{
+			name:   "one failing",
+			checks: map[string]CheckFunc{"database": ok, "relay": failing},
+			want: Report{Status: StatusFailing, Components: map[string]Component{
+				"database": {Status: StatusOK},
+				"relay":    {Status: StatusFailing, Error: "connection refused"},
+			}},
+		},
+		{
+			name:   "check timeout",
+			checks: map[string]CheckFunc{"database": hanging},
+			want: Report{Status: StatusFailing, Components: map[string]Component{
+				"database": {Status: StatusFailing, Error: context.DeadlineExceeded.Error()},
+			}},
+		},
+		{
+			name:     "draining overrides ok",
+			checks:   map[string]CheckFunc{"database": ok},
+			draining: true,
+			want: Report{Status: StatusDraining, Components: map[string]Component{
+				"database": {Status: StatusOK},
+			}},
+		},
+		{
+			name:     "draining overrides failing",
+			checks:   map[string]CheckFunc{"database": failing},
+			draining: true,
+			want: Report{Status: StatusDraining, Components: map[string]Component{
+				"database": {Status: StatusFailing, Error: "connection refused"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(20 * time.Millisecond)
+			for name, fn := range tt.checks {
+				c.Add(name, fn)
+			}
+			if tt.draining {
+				c.Drain()
+			}
+
+			got := c.Check(context.Background())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %+v, want %+v", got, tt.want)
+			}
+			if got.Ready() != (tt.want.Status == StatusOK) {
+				t.Errorf("Ready() = %v for status %q", got.Ready(), got.Status)
+			}
+		})
+	}
+}
+
+func TestCheckerRunsChecksInParallel(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	c := NewChecker(timeout)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		c.Add(name, func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}
+
+	start := time.Now()
+	c.Check(context.Background())
+	if elapsed := time.Since(start); elapsed > 3*timeout {
+		t.Errorf("Check() took %s, want about %s for parallel checks", elapsed, timeout)
+	}
+}
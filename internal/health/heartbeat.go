@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Heartbeat отслеживает успешные итерации фоновой задачи. Задача считается
+// неисправной, если дольше tolerance не было ни одной успешной итерации
+type Heartbeat struct {
+	tolerance time.Duration
+
+	mu      sync.Mutex
+	last    time.Time // последняя успешная итерация или запуск
+	lastErr error
+}
+
+func NewHeartbeat(tolerance time.Duration) *Heartbeat {
+	return &Heartbeat{tolerance: tolerance, last: time.Now()}
+}
+
+// Beat отмечает успешную итерацию
+func (h *Heartbeat) Beat() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = time.Now()
+	h.lastErr = nil
+}
+
+// Fail запоминает ошибку итерации
+func (h *Heartbeat) Fail(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastErr = err
+}
+
+// Check реализует CheckFunc
+func (h *Heartbeat) Check(context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	since := time.Since(h.last)
+	if since <= h.tolerance {
+		return nil
+	}
+	if h.lastErr != nil {
+		return fmt.Errorf("no successful run for %s: %w", since.Round(time.Second), h.lastErr)
+	}
+	return fmt.Errorf("no successful run for %s", since.Round(time.Second))
+}
@@ -0,0 +1,67 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	errRun := errors.New("query failed")
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration // время с последней успешной итерации
+		err     error         // ошибка последней итерации
+		wantErr string
+	}{
+		{name: "just started", elapsed: 0},
+		{name: "within tolerance", elapsed: 59 * time.Second},
+		{name: "error within tolerance", elapsed: 30 * time.Second, err: errRun},
+		{name: "beyond tolerance", elapsed: 2 * time.Minute, wantErr: "no successful run for 2m0s"},
+		{name: "beyond tolerance with error", elapsed: 2 * time.Minute, err: errRun, wantErr: "no successful run for 2m0s: query failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHeartbeat(time.Minute)
+			h.last = time.Now().Add(-tt.elapsed)
+			if tt.err != nil {
+				h.Fail(tt.err)
+			}
+
+			err := h.Check(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Check() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("Check() error does not wrap %v", tt.err)
+			}
+		})
+	}
+}
+
+func TestHeartbeatBeatClearsError(t *testing.T) {
+	h := NewHeartbeat(time.Minute)
+	h.last = time.Now().Add(-time.Hour)
+	h.Fail(errors.New("query failed"))
+	if err := h.Check(context.Background()); err == nil {
+		t.Fatal("Check() = nil for a stale heartbeat")
+	}
+
+	h.Beat()
+	if err := h.Check(context.Background()); err != nil {
+		t.Fatalf("Check() after Beat error = %v", err)
+	}
+	if h.lastErr != nil {
+		t.Errorf("lastErr = %v after Beat, want nil", h.lastErr)
+	}
+}
@@ -0,0 +1,22 @@
+package httputil
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ResponseStatus возвращает статус ответа для middleware, работающих до
+// обработчика ошибок echo. Ошибку обработчика echo превратит в ответ позже,
+// поэтому статус берется из нее
+func ResponseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,138 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType - MIME-тип календаря
+const ContentType = "text/calendar; charset=utf-8"
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405Z"
+	// максимальная длина строки контента без CRLF (RFC 5545, 3.1)
+	maxLineOctets = 75
+)
+
+// Calendar - объект VCALENDAR
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Event - объект VEVENT на целый день
+type Event struct {
+	UID          string
+	Stamp        time.Time
+	LastModified time.Time
+	Date         time.Time
+	Summary      string
+	Description  string
+	Recurrence   *Recurrence
+}
+
+// Recurrence - правило повторения RRULE
+type Recurrence struct {
+	Freq  string     // DAILY, WEEKLY, MONTHLY, YEARLY
+	Until *time.Time // включительно; nil - бессрочно
+}
+
+const FreqMonthly = "MONTHLY"
+
+// Encode сериализует календарь в формате RFC 5545
+func (c *Calendar) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	lw := &lineWriter{w: bw}
+
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:" + c.ProdID)
+	lw.line("CALSCALE:GREGORIAN")
+	lw.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME:" + escapeText(c.Name))
+	}
+	for i := range c.Events {
+		c.Events[i].encode(lw)
+	}
+	lw.line("END:VCALENDAR")
+
+	if lw.err != nil {
+		return lw.err
+	}
+	return bw.Flush()
+}
+
+func (e *Event) encode(lw *lineWriter) {
+	lw.line("BEGIN:VEVENT")
+	lw.line("UID:" + e.UID)
+	lw.line("DTSTAMP:" + e.Stamp.UTC().Format(dateTimeLayout))
+	if !e.LastModified.IsZero() {
+		lw.line("LAST-MODIFIED:" + e.LastModified.UTC().Format(dateTimeLayout))
+	}
+	lw.line("DTSTART;VALUE=DATE:" + e.Date.Format(dateLayout))
+	lw.line("DTEND;VALUE=DATE:" + e.Date.AddDate(0, 0, 1).Format(dateLayout))
+	if e.Recurrence != nil {
+		rule := "RRULE:FREQ=" + e.Recurrence.Freq
+		if e.Recurrence.Until != nil {
+			// для DTSTART типа DATE значение UNTIL тоже должно быть DATE
+			rule += ";UNTIL=" + e.Recurrence.Until.Format(dateLayout)
+		}
+		lw.line(rule)
+	}
+	lw.line("SUMMARY:" + escapeText(e.Summary))
+	if e.Description != "" {
+		lw.line("DESCRIPTION:" + escapeText(e.Description))
+	}
+	lw.line("TRANSP:TRANSPARENT")
+	lw.line("END:VEVENT")
+}
+
+// escapeText экранирует значение типа TEXT (RFC 5545, 3.3.11)
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`, // одиночный CR внутри строки контента запрещен (3.1)
+	).Replace(s)
+}
+
+// lineWriter пишет строки контента с CRLF и переносом длинных строк
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+	limit := maxLineOctets
+	for len(s) > limit {
+		// не разрываем многобайтовые символы UTF-8
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		lw.write(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// строка продолжения начинается с пробела, он входит в лимит
+		limit = maxLineOctets - 1
+	}
+	lw.write(s + "\r\n")
+}
+
+func (lw *lineWriter) write(s string) {
+	if lw.err != nil {
+		return
+	}
+	_, lw.err = lw.w.WriteString(s)
+}
@@ -0,0 +1,127 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestLineFolding(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantLines int
+	}{
+		{name: "short", line: "SUMMARY:Netflix", wantLines: 1},
+		{name: "exactly the limit", line: strings.Repeat("a", maxLineOctets), wantLines: 1},
+		{name: "one octet over", line: strings.Repeat("a", maxLineOctets+1), wantLines: 2},
+		{name: "continuation limit includes leading space", line: strings.Repeat("a", maxLineOctets+maxLineOctets-1+1), wantLines: 3},
+		{name: "long ascii", line: "DESCRIPTION:" + strings.Repeat("x", 300), wantLines: 5},
+		{name: "cyrillic is not split", line: "SUMMARY:" + strings.Repeat("Подписка ", 20), wantLines: 5},
+		{name: "emoji is not split", line: "SUMMARY:" + strings.Repeat("🎬", 40), wantLines: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			bw := bufio.NewWriter(&buf)
+			lw := &lineWriter{w: bw}
+			lw.line(tt.line)
+			if err := bw.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			out := buf.String()
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("output %q does not end with CRLF", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			if len(lines) != tt.wantLines {
+				t.Errorf("got %d lines, want %d", len(lines), tt.wantLines)
+			}
+			for i, l := range lines {
+				if len(l) > maxLineOctets {
+					t.Errorf("line %d is %d octets, limit is %d", i, len(l), maxLineOctets)
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("line %d splits a UTF-8 character: %q", i, l)
+				}
+				if i > 0 && !strings.HasPrefix(l, " ") {
+					t.Errorf("continuation line %d does not start with a space: %q", i, l)
+				}
+			}
+
+			// разворачивание по RFC 5545 возвращает исходную строку
+			if unfolded := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); unfolded != tt.line {
+				t.Errorf("unfolded = %q, want %q", unfolded, tt.line)
+			}
+		})
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Netflix", want: "Netflix"},
+		{in: "Yandex Plus; семейная", want: `Yandex Plus\; семейная`},
+		{in: "a,b", want: `a\,b`},
+		{in: `C:\path`, want: `C:\\path`},
+		{in: "line1\nline2", want: `line1\nline2`},
+		{in: "line1\r\nline2", want: `line1\nline2`},
+		{in: "line1\rline2", want: `line1\nline2`},
+		{in: "line1\n\rline2", want: `line1\n\nline2`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeText(tt.in); got != tt.want {
+			t.Errorf("escapeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCalendarEncode(t *testing.T) {
+	until := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	cal := &Calendar{
+		ProdID: "-//test//EN",
+		Name:   "Подписки",
+		Events: []Event{{
+			UID:        "sub-1@test",
+			Stamp:      time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC),
+			Date:       time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			Summary:    "Netflix, 400 RUB",
+			Recurrence: &Recurrence{Freq: FreqMonthly, Until: &until},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := cal.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//test//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:Подписки",
+		"BEGIN:VEVENT",
+		"UID:sub-1@test",
+		"DTSTAMP:20260115T103000Z",
+		"DTSTART;VALUE=DATE:20260201",
+		"DTEND;VALUE=DATE:20260202",
+		"RRULE:FREQ=MONTHLY;UNTIL=20260601",
+		`SUMMARY:Netflix\, 400 RUB`,
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n") + "\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Encode() =\n%s\nwant\n%s", got, want)
+	}
+}
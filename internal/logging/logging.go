@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Config - параметры логгера
+type Config struct {
+	Level            string // debug, info, warn, error
+	Format           string // json, console
+	Development      bool   // стек вызовов с уровня warn вместо error, DPanic паникует
+	OutputPaths      []string
+	ErrorOutputPaths []string // внутренние ошибки самого логгера
+	// Sampling: в течение секунды пишутся первые SamplingInitial одинаковых
+	// сообщений, затем каждое SamplingThereafter-е. SamplingInitial = 0 выключает
+	SamplingInitial    int
+	SamplingThereafter int
+}
+
+// New собирает логгер по конфигурации. Уровень возвращается отдельно,
+// чтобы его можно было менять без перезапуска
+func New(cfg Config) (*zap.Logger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Level)
+	if err != nil {
+		return nil, level, fmt.Errorf("invalid log level: %w", err)
+	}
+
+	encoder := zap.NewProductionEncoderConfig()
+	if cfg.Development {
+		encoder = zap.NewDevelopmentEncoderConfig()
+	}
+	encoder.TimeKey = "ts"
+	encoder.EncodeTime = zapcore.ISO8601TimeEncoder
+	if cfg.Format == "console" {
+		encoder.EncodeLevel = zapcore.CapitalLevelEncoder
+	}
+
+	zcfg := zap.Config{
+		Level:            level,
+		Development:      cfg.Development,
+		Encoding:         cfg.Format,
+		EncoderConfig:    encoder,
+		OutputPaths:      cfg.OutputPaths,
+		ErrorOutputPaths: cfg.ErrorOutputPaths,
+	}
+	if len(zcfg.OutputPaths) == 0 {
+		zcfg.OutputPaths = []string{"stdout"}
+	}
+	if len(zcfg.ErrorOutputPaths) == 0 {
+		zcfg.ErrorOutputPaths = []string{"stderr"}
+	}
+	if cfg.SamplingInitial > 0 {
+		zcfg.Sampling = &zap.SamplingConfig{Initial: cfg.SamplingInitial, Thereafter: cfg.SamplingThereafter}
+	}
+
+	log, err := zcfg.Build()
+	if err != nil {
+		return nil, level, fmt.Errorf("failed to build logger: %w", err)
+	}
+	return log, level, nil
+}
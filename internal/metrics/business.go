@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"go.uber.org/zap"
+)
+
+// BusinessCollector периодически обновляет показатели подписок: число
+// действующих в текущем месяце подписок и их суммарную стоимость в месяц
+type BusinessCollector struct {
+	repo     repository.StatsRepository
+	metrics  *Metrics
+	interval time.Duration
+	log      *zap.Logger
+}
+
+func NewBusinessCollector(repo repository.StatsRepository, m *Metrics, interval time.Duration, log *zap.Logger) *BusinessCollector {
+	return &BusinessCollector{repo: repo, metrics: m, interval: interval, log: log}
+}
+
+// Run обновляет показатели сразу и затем каждые interval до отмены ctx
+func (b *BusinessCollector) Run(ctx context.Context) {
+	b.log.Info("business metrics collector started", zap.Duration("interval", b.interval))
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		b.collect(ctx)
+
+		select {
+		case <-ctx.Done():
+			b.log.Info("business metrics collector stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *BusinessCollector) collect(ctx context.Context) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	stats, err := b.repo.Stats(ctx, month)
+	if err != nil {
+		if ctx.Err() == nil {
+			b.log.Error("collect business metrics failed", zap.Error(err))
+		}
+		return
+	}
+
+	// организации без действующих подписок пропадают из метрик
+	b.metrics.active.Reset()
+	b.metrics.monthlySpend.Reset()
+	for _, s := range stats {
+		b.metrics.active.WithLabelValues(s.TenantID).Set(float64(s.Active))
+		b.metrics.monthlySpend.WithLabelValues(s.TenantID).Set(float64(s.MonthlySpend))
+	}
+}
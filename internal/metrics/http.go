@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/httputil"
+)
+
+// unmatchedRoute - метка запросов, не попавших ни в один маршрут. Путь запроса
+// в метку не попадает, чтобы сканеры не раздували число рядов
+const unmatchedRoute = "unmatched"
+
+// Middleware считает HTTP-запросы и их длительность по шаблону маршрута и статусу
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			status := strconv.Itoa(httputil.ResponseStatus(c, err))
+			method := c.Request().Method
+
+			m.httpRequests.WithLabelValues(method, route, status).Inc()
+			m.httpDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics - метрики сервиса в собственном реестре
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests  *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	queryDuration *prometheus.HistogramVec
+	active        *prometheus.GaugeVec
+	monthlySpend  *prometheus.GaugeVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "repository_query_duration_seconds",
+			Help:    "Repository method latency.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"repository", "method", "result"}),
+		active: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "subscriptions_active",
+			Help: "Subscriptions active in the current month.",
+		}, []string{"tenant"}),
+		monthlySpend: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "subscriptions_monthly_spend",
+			Help: "Total monthly price of subscriptions active in the current month.",
+		}, []string{"tenant"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.queryDuration,
+		m.active,
+		m.monthlySpend,
+	)
+	return m
+}
+
+// MustRegister добавляет коллекторы в реестр сервиса
+func (m *Metrics) MustRegister(cs ...prometheus.Collector) {
+	m.registry.MustRegister(cs...)
+}
+
+// Handler отдает метрики в формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolAcquiredDesc = prometheus.NewDesc("pgxpool_acquired_conns",
+		"Connections currently acquired from the pool.", nil, nil)
+	poolIdleDesc = prometheus.NewDesc("pgxpool_idle_conns",
+		"Idle connections in the pool.", nil, nil)
+	poolTotalDesc = prometheus.NewDesc("pgxpool_total_conns",
+		"Total connections in the pool.", nil, nil)
+	poolMaxDesc = prometheus.NewDesc("pgxpool_max_conns",
+		"Maximum size of the pool.", nil, nil)
+	poolAcquireCountDesc = prometheus.NewDesc("pgxpool_acquire_count_total",
+		"Successful connection acquires.", nil, nil)
+	poolAcquireDurationDesc = prometheus.NewDesc("pgxpool_acquire_duration_seconds_total",
+		"Total time spent acquiring connections.", nil, nil)
+	poolEmptyAcquireDesc = prometheus.NewDesc("pgxpool_empty_acquire_count_total",
+		"Acquires that had to wait for a connection because the pool was empty.", nil, nil)
+	poolEmptyAcquireWaitDesc = prometheus.NewDesc("pgxpool_empty_acquire_wait_seconds_total",
+		"Total time spent waiting for a connection because the pool was empty.", nil, nil)
+	poolCanceledAcquireDesc = prometheus.NewDesc("pgxpool_canceled_acquire_count_total",
+		"Acquires canceled by context.", nil, nil)
+)
+
+// PoolCollector снимает статистику пула соединений при каждом сборе метрик
+type PoolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	return &PoolCollector{pool: pool}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredDesc
+	ch <- poolIdleDesc
+	ch <- poolTotalDesc
+	ch <- poolMaxDesc
+	ch <- poolAcquireCountDesc
+	ch <- poolAcquireDurationDesc
+	ch <- poolEmptyAcquireDesc
+	ch <- poolEmptyAcquireWaitDesc
+	ch <- poolCanceledAcquireDesc
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(poolAcquiredDesc, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalDesc, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxDesc, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireCountDesc, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDurationDesc, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquireDesc, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquireWaitDesc, prometheus.CounterValue, s.EmptyAcquireWaitTime().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolCanceledAcquireDesc, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+)
+
+// observe записывает длительность метода репозитория
+func (m *Metrics) observe(repo, method string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.queryDuration.WithLabelValues(repo, method, result).Observe(time.Since(start).Seconds())
+}
+
+// SubscriptionRepo измеряет длительность методов репозитория подписок
+type SubscriptionRepo struct {
+	next    repository.SubscriptionRepository
+	metrics *Metrics
+}
+
+func NewSubscriptionRepo(next repository.SubscriptionRepository, m *Metrics) *SubscriptionRepo {
+	return &SubscriptionRepo{next: next, metrics: m}
+}
+
+func (r *SubscriptionRepo) Create(ctx context.Context, sub *models.Subscription) (err error) {
+	defer func(start time.Time) { r.metrics.observe("subscriptions", "Create", start, err) }(time.Now())
+	return r.next.Create(ctx, sub)
+}
+
+func (r *SubscriptionRepo) GetByID(ctx context.Context, id uuid.UUID, opts models.GetOptions) (_ *models.Subscription, err error) {
+	defer func(start time.Time) { r.metrics.observe("subscriptions", "GetByID", start, err) }(time.Now())
+	return r.next.GetByID(ctx, id, opts)
+}
+
+func (r *SubscriptionRepo) Update(ctx context.Context, sub *models.Subscription) (err error) {
+	defer func(start time.Time) { r.metrics.observe("subscriptions", "Update", start, err) }(time.Now())
+	return r.next.Update(ctx, sub)
+}
+
+func (r *SubscriptionRepo) Delete(ctx context.Context, id uuid.UUID) (_ *models.Subscription, err error) {
+	defer func(start time.Time) { r.metrics.observe("subscriptions", "Delete", start, err) }(time.Now())
+	return r.next.Delete(ctx, id)
+}
+
+func (r *SubscriptionRepo) Restore(ctx context.Context, id uuid.UUID) (_ *models.Subscription, err error) {
+	defer func(start time.Time) { r.metrics.observe("subscriptions", "Restore", start, err) }(time.Now())
+	return r.next.Restore(ctx, id)
+}
+
+func (r *SubscriptionRepo) PurgeDeleted(ctx context.Context, before time.Time) (_ int64, err error) {
+	defer func(start time.Time) { r.metrics.observe("subscriptions", "PurgeDeleted", start, err) }(time.Now())
+	return r.next.PurgeDeleted(ctx, before)
+}
+
+func (r *SubscriptionRepo) List(ctx context.Context, filter models.SubscriptionFilter) (_ []*models.Subscription, err error) {
+	defer func(start time.Time) { r.metrics.observe("subscriptions", "List", start, err) }(time.Now())
+	return r.next.List(ctx, filter)
+}
+
+// Stream измеряется целиком, вместе с обработкой строк в fn
+func (r *SubscriptionRepo) Stream(ctx context.Context, filter models.SubscriptionFilter, fn func(*models.Subscription) error) (err error) {
+	defer func(start time.Time) { r.metrics.observe("subscriptions", "Stream", start, err) }(time.Now())
+	return r.next.Stream(ctx, filter, fn)
+}
+
+func (r *SubscriptionRepo) CalculateCost(ctx context.Context, filter models.CostFilter) (_ int, err error) {
+	defer func(start time.Time) { r.metrics.observe("subscriptions", "CalculateCost", start, err) }(time.Now())
+	return r.next.CalculateCost(ctx, filter)
+}
+
+// AuditRepo измеряет длительность методов журнала изменений
+type AuditRepo struct {
+	next    repository.AuditRepository
+	metrics *Metrics
+}
+
+func NewAuditRepo(next repository.AuditRepository, m *Metrics) *AuditRepo {
+	return &AuditRepo{next: next, metrics: m}
+}
+
+func (r *AuditRepo) ListAudit(ctx context.Context, filter models.AuditFilter) (_ []*models.AuditEntry, err error) {
+	defer func(start time.Time) { r.metrics.observe("audit", "ListAudit", start, err) }(time.Now())
+	return r.next.ListAudit(ctx, filter)
+}
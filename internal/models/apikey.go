@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scope - право, выдаваемое API-ключу
+type Scope string
+
+const (
+	ScopeSubscriptionsRead  Scope = "subscriptions:read"
+	ScopeSubscriptionsWrite Scope = "subscriptions:write"
+	ScopeReportsRead        Scope = "reports:read"
+)
+
+// Scopes - все известные права
+var Scopes = []Scope{
+	ScopeSubscriptionsRead,
+	ScopeSubscriptionsWrite,
+	ScopeReportsRead,
+}
+
+// Valid сообщает, известно ли право
+func (s Scope) Valid() bool {
+	for _, known := range Scopes {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey - ключ доступа для сервисных клиентов. Сам ключ не хранится,
+// только его хеш; Prefix служит для поиска ключа и отображения
+// swagger:model APIKey
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	TenantID   string     `json:"tenant_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Hash       []byte     `json:"-"`
+	Scopes     []Scope    `json:"scopes"`
+	UserID     *uuid.UUID `json:"user_id,omitempty"` // nil - доступ к подпискам всех пользователей
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Active сообщает, действует ли ключ в момент now
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
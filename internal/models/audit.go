@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditAction - тип изменения подписки
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditPurge   AuditAction = "purge"
+)
+
+// AuditEntry - запись журнала изменений подписки
+// swagger:model AuditEntry
+type AuditEntry struct {
+	ID             int64                  `json:"id"`
+	SubscriptionID uuid.UUID              `json:"subscription_id"`
+	Action         AuditAction            `json:"action"`
+	Actor          string                 `json:"actor"`
+	RequestID      string                 `json:"request_id,omitempty"`
+	Diff           map[string]FieldChange `json:"diff"`
+	CreatedAt      time.Time              `json:"created_at"`
+}
+
+// FieldChange - значение поля до и после изменения
+// swagger:model FieldChange
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditFilter - фильтры для выборки журнала изменений
+type AuditFilter struct {
+	SubscriptionID *uuid.UUID
+	Actor          *string
+	Action         *AuditAction
+	From           *time.Time
+	To             *time.Time
+	Limit          int
+	Offset         int
+}
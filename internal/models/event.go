@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType - тип события изменения подписки
+type EventType string
+
+const (
+	EventSubscriptionCreated  EventType = "subscription.created"
+	EventSubscriptionUpdated  EventType = "subscription.updated"
+	EventSubscriptionDeleted  EventType = "subscription.deleted"
+	EventSubscriptionRenewing EventType = "subscription.renewing"
+)
+
+// EventTypes - все известные типы событий
+var EventTypes = []EventType{
+	EventSubscriptionCreated,
+	EventSubscriptionUpdated,
+	EventSubscriptionDeleted,
+	EventSubscriptionRenewing,
+}
+
+// Valid сообщает, известен ли тип события
+func (t EventType) Valid() bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event - событие изменения подписки. ID уникален для события
+// и используется получателями для дедупликации
+// swagger:model Event
+type Event struct {
+	ID             string          `json:"id"`
+	Type           EventType       `json:"type"`
+	TenantID       string          `json:"tenant_id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	OccurredAt     time.Time       `json:"occurred_at"`
+	Data           json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// NewEvent создает событие организации tenantID о подписке subscriptionID
+// с данными data, сериализованными в JSON
+func NewEvent(id string, t EventType, tenantID string, subscriptionID uuid.UUID, data interface{}) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:             id,
+		Type:           t,
+		TenantID:       tenantID,
+		SubscriptionID: subscriptionID,
+		OccurredAt:     time.Now().UTC(),
+		Data:           raw,
+	}, nil
+}
+
+// OutboxRecord - событие в outbox, ожидающее публикации
+type OutboxRecord struct {
+	ID       int64
+	Event    Event
+	Attempts int
+}
+
+// StreamEvent - событие в потоке изменений. Seq - номер записи в outbox,
+// служит идентификатором для возобновления потока (Last-Event-ID)
+type StreamEvent struct {
+	Seq   int64 `json:"seq"`
+	Event Event `json:"event"`
+}
+
+// RenewalData - данные события subscription.renewing
+// swagger:model RenewalData
+type RenewalData struct {
+	Subscription *Subscription `json:"subscription"`
+	RenewalDate  string        `json:"renewal_date"` // формат: MM-YYYY
+}
@@ -10,6 +10,7 @@ import (
 // swagger:model Subscription
 type Subscription struct {
 	ID          uuid.UUID  `json:"id"`
+	TenantID    string     `json:"tenant_id"`
 	ServiceName string     `json:"service_name"`
 	Price       int        `json:"price"`
 	UserID      uuid.UUID  `json:"user_id"`
@@ -17,6 +18,7 @@ type Subscription struct {
 	EndDate     *time.Time `json:"end_date,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 }
 
 // CreateSubscriptionDTO - входные данные для создания подписки
@@ -32,26 +34,42 @@ type CreateSubscriptionDTO struct {
 // UpdateSubscriptionDTO - входные данные для обновления подписки
 // swagger:model UpdateSubscriptionDTO
 type UpdateSubscriptionDTO struct {
-	ServiceName *string    `json:"service_name,omitempty" validate:"omitempty,min=1,max=255"`
-	Price       *int       `json:"price,omitempty" validate:"omitempty,gt=0"`
-	StartDate   *string    `json:"start_date,omitempty"` // формат: MM-YYYY
-	EndDate     *string    `json:"end_date,omitempty"`   // формат: MM-YYYY
+	ServiceName *string `json:"service_name,omitempty" validate:"omitempty,min=1,max=255"`
+	Price       *int    `json:"price,omitempty" validate:"omitempty,gt=0"`
+	StartDate   *string `json:"start_date,omitempty"` // формат: MM-YYYY
+	EndDate     *string `json:"end_date,omitempty"`   // формат: MM-YYYY
 }
 
 // SubscriptionFilter - фильтры для выборки подписок
 // swagger:model SubscriptionFilter
 type SubscriptionFilter struct {
-	UserID      *uuid.UUID
-	ServiceName *string
-	Limit       int
-	Offset      int
+	UserID         *uuid.UUID
+	ServiceName    *string
+	ActiveOn       *time.Time // действует в указанный месяц
+	IncludeDeleted bool
+	AllTenants     bool // без ограничения организацией запроса; только для фоновых задач
+	Limit          int
+	Offset         int
 }
 
 // CostFilter - фильтры для подсчета стоимости
 // swagger:model CostFilter
 type CostFilter struct {
-	UserID      *uuid.UUID
-	ServiceName *string
-	StartPeriod time.Time
-	EndPeriod   time.Time
-}
\ No newline at end of file
+	UserID         *uuid.UUID
+	ServiceName    *string
+	StartPeriod    time.Time
+	EndPeriod      time.Time
+	IncludeDeleted bool
+}
+
+// GetOptions - параметры выборки одной подписки
+type GetOptions struct {
+	IncludeDeleted bool
+}
+
+// SubscriptionStats - сводка по действующим подпискам организации за месяц
+type SubscriptionStats struct {
+	TenantID     string
+	Active       int64 // число действующих подписок
+	MonthlySpend int64 // суммарная стоимость действующих подписок в месяц
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook - зарегистрированный получатель событий
+// swagger:model Webhook
+type Webhook struct {
+	ID         uuid.UUID   `json:"id"`
+	TenantID   string      `json:"tenant_id"`
+	URL        string      `json:"url"`
+	Secret     string      `json:"-"`
+	EventTypes []EventType `json:"event_types"`
+	Active     bool        `json:"active"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// Subscribed сообщает, подписан ли получатель на событие
+func (w *Webhook) Subscribed(t EventType) bool {
+	for _, et := range w.EventTypes {
+		if et == t {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryStatus - состояние доставки события получателю
+type DeliveryStatus string
+
+const (
+	// DeliveryPending - ожидает первой или повторной попытки
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliveryDelivered - получатель ответил 2xx
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead - попытки исчерпаны, доставка отправлена в dead letter
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// WebhookDelivery - доставка одного события одному получателю
+// swagger:model WebhookDelivery
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	WebhookID      uuid.UUID       `json:"webhook_id"`
+	TenantID       string          `json:"-"`
+	EventID        string          `json:"event_id"`
+	EventType      EventType       `json:"event_type"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastError      *string         `json:"last_error,omitempty"`
+	ResponseStatus *int            `json:"response_status,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// DeliveryFilter - фильтры для журнала доставок
+type DeliveryFilter struct {
+	WebhookID uuid.UUID
+	Status    *DeliveryStatus
+	Limit     int
+	Offset    int
+}
+
+// DeliveryResult - итог попытки доставки
+type DeliveryResult struct {
+	ResponseStatus *int
+	Err            error
+	Dead           bool      // попытки исчерпаны
+	NextAttemptAt  time.Time // время следующей попытки, если не Dead
+}
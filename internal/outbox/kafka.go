@@ -0,0 +1,54 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/untibullet/subscription-service-em/internal/models"
+)
+
+// KafkaPublisher публикует события в топик Kafka. Ключ сообщения - ID подписки,
+// поэтому события одной подписки попадают в одну партицию и сохраняют порядок
+type KafkaPublisher struct {
+	w messageWriter
+}
+
+// messageWriter - часть kafka.Writer, которой пользуется KafkaPublisher
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+func NewKafkaPublisher(brokers []string, topic string) *KafkaPublisher {
+	return &KafkaPublisher{w: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}}
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, event models.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	msg := kafka.Message{
+		Key:   []byte(event.SubscriptionID.String()),
+		Value: data,
+		Headers: []kafka.Header{
+			{Key: "event_id", Value: []byte(event.ID)},
+			{Key: "event_type", Value: []byte(event.Type)},
+		},
+	}
+	if err := p.w.WriteMessages(ctx, msg); err != nil {
+		return fmt.Errorf("kafka publish: %w", err)
+	}
+	return nil
+}
+
+func (p *KafkaPublisher) Close() error {
+	return p.w.Close()
+}
@@ -0,0 +1,56 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/untibullet/subscription-service-em/internal/models"
+)
+
+// NATSPublisher публикует события в NATS JetStream в subject
+// "<prefix>.<event type>". ID события передается как Nats-Msg-Id, поэтому
+// повторы в пределах окна дедупликации стрима отбрасываются сервером
+type NATSPublisher struct {
+	conn   *nats.Conn
+	js     jetstream.JetStream
+	prefix string
+}
+
+// NewNATSPublisher подключается к NATS. Стрим, покрывающий "<prefix>.>",
+// должен существовать: без него публикация не подтверждается
+func NewNATSPublisher(url, prefix string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("subscription-service-outbox"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to init jetstream: %w", err)
+	}
+	return &NATSPublisher{conn: conn, js: js, prefix: prefix}, nil
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, event models.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	msg := &nats.Msg{
+		Subject: p.prefix + "." + string(event.Type),
+		Data:    data,
+		Header:  nats.Header{jetstream.MsgIDHeader: []string{event.ID}},
+	}
+	if _, err := p.js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("nats publish: %w", err)
+	}
+	return nil
+}
+
+// Close дожидается отправки буфера и закрывает соединение
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
@@ -0,0 +1,69 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"go.uber.org/zap"
+)
+
+// LogPublisher пишет события в лог. Полезен для локальной разработки
+type LogPublisher struct {
+	log *zap.Logger
+}
+
+func NewLogPublisher(log *zap.Logger) *LogPublisher {
+	return &LogPublisher{log: log}
+}
+
+func (p *LogPublisher) Publish(_ context.Context, event models.Event) error {
+	p.log.Info("event published",
+		zap.String("event_id", event.ID),
+		zap.String("event_type", string(event.Type)),
+		zap.String("subscription_id", event.SubscriptionID.String()),
+		zap.Time("occurred_at", event.OccurredAt),
+		zap.ByteString("data", event.Data),
+	)
+	return nil
+}
+
+// MemoryPublisher хранит опубликованные события в памяти процесса.
+// Предназначен для тестов и встраивания
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []models.Event
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(_ context.Context, event models.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+// Events возвращает копию опубликованных событий
+func (p *MemoryPublisher) Events() []models.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]models.Event(nil), p.events...)
+}
+
+// MultiPublisher публикует событие во все адаптеры. Ошибка любого из них
+// приводит к повторной публикации во все, что допустимо при at-least-once
+type MultiPublisher []EventPublisher
+
+func (m MultiPublisher) Publish(ctx context.Context, event models.Event) error {
+	var errs []error
+	for _, p := range m {
+		if err := p.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
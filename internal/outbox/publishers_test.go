@@ -0,0 +1,120 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/segmentio/kafka-go"
+	"github.com/untibullet/subscription-service-em/internal/models"
+)
+
+func testEvent() models.Event {
+	return models.Event{
+		ID:             "evt-1",
+		Type:           models.EventSubscriptionUpdated,
+		TenantID:       "acme",
+		SubscriptionID: uuid.MustParse("0b7e3a7c-7c1f-4a53-9f7c-1d2e3f4a5b6c"),
+		Data:           json.RawMessage(`{"price":400}`),
+	}
+}
+
+// fakeJetStream запоминает опубликованные сообщения
+type fakeJetStream struct {
+	jetstream.JetStream
+	msgs []*nats.Msg
+	err  error
+}
+
+func (js *fakeJetStream) PublishMsg(_ context.Context, msg *nats.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	if js.err != nil {
+		return nil, js.err
+	}
+	js.msgs = append(js.msgs, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+func TestNATSPublisher(t *testing.T) {
+	js := &fakeJetStream{}
+	p := &NATSPublisher{js: js, prefix: "subscriptions"}
+
+	event := testEvent()
+	if err := p.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if len(js.msgs) != 1 {
+		t.Fatalf("published %d messages, want 1", len(js.msgs))
+	}
+	msg := js.msgs[0]
+	if msg.Subject != "subscriptions.subscription.updated" {
+		t.Errorf("subject = %q", msg.Subject)
+	}
+	// сервер отбрасывает повторы по идентификатору события
+	if got := msg.Header.Get(jetstream.MsgIDHeader); got != event.ID {
+		t.Errorf("%s = %q, want %q", jetstream.MsgIDHeader, got, event.ID)
+	}
+	var got models.Event
+	if err := json.Unmarshal(msg.Data, &got); err != nil || got.ID != event.ID || got.TenantID != "acme" {
+		t.Errorf("data = %s, err = %v", msg.Data, err)
+	}
+
+	// неподтвержденная публикация - ошибка, событие будет опубликовано повторно
+	js.err = errors.New("no stream matches subject")
+	if err := p.Publish(context.Background(), event); !errors.Is(err, js.err) {
+		t.Errorf("Publish() error = %v, want %v", err, js.err)
+	}
+}
+
+// fakeKafkaWriter запоминает записанные сообщения
+type fakeKafkaWriter struct {
+	msgs []kafka.Message
+	err  error
+}
+
+func (w *fakeKafkaWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error { return nil }
+
+func TestKafkaPublisher(t *testing.T) {
+	w := &fakeKafkaWriter{}
+	p := &KafkaPublisher{w: w}
+
+	event := testEvent()
+	if err := p.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.msgs) != 1 {
+		t.Fatalf("wrote %d messages, want 1", len(w.msgs))
+	}
+	msg := w.msgs[0]
+	// события одной подписки попадают в одну партицию
+	if string(msg.Key) != event.SubscriptionID.String() {
+		t.Errorf("key = %q, want subscription id", msg.Key)
+	}
+	headers := map[string]string{}
+	for _, h := range msg.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	if headers["event_id"] != event.ID || headers["event_type"] != string(event.Type) {
+		t.Errorf("headers = %v", headers)
+	}
+	var got models.Event
+	if err := json.Unmarshal(msg.Value, &got); err != nil || got.ID != event.ID {
+		t.Errorf("value = %s, err = %v", msg.Value, err)
+	}
+
+	w.err = kafka.LeaderNotAvailable
+	if err := p.Publish(context.Background(), event); !errors.Is(err, kafka.LeaderNotAvailable) {
+		t.Errorf("Publish() error = %v, want %v", err, kafka.LeaderNotAvailable)
+	}
+}
@@ -0,0 +1,153 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/untibullet/subscription-service-em/internal/health"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"go.uber.org/zap"
+)
+
+// EventPublisher публикует событие во внешнюю систему. Публикация считается
+// успешной, только если событие принято получателем; при ошибке событие будет
+// опубликовано повторно, поэтому потребители должны дедуплицировать по Event.ID
+type EventPublisher interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// RelayConfig - параметры публикации событий из outbox
+type RelayConfig struct {
+	PollInterval    time.Duration // как часто проверять outbox
+	BatchSize       int           // сколько событий брать за раз
+	Lease           time.Duration // на сколько откладывать взятые события
+	InitialBackoff  time.Duration // задержка перед повторной публикацией
+	MaxBackoff      time.Duration // верхняя граница задержки
+	Retention       time.Duration // сколько хранить опубликованные события
+	CleanupInterval time.Duration // как часто удалять опубликованные события
+}
+
+// Relay публикует события из outbox через EventPublisher с гарантией
+// доставки at-least-once и удаляет опубликованные события по истечении Retention
+type Relay struct {
+	repo repository.OutboxRepository
+	pub  EventPublisher
+	cfg  RelayConfig
+	beat *health.Heartbeat
+	log  *zap.Logger
+}
+
+func NewRelay(repo repository.OutboxRepository, pub EventPublisher, cfg RelayConfig, log *zap.Logger) *Relay {
+	// пачка публикуется не дольше lease, иначе события уйдут повторно
+	beat := health.NewHeartbeat(health.MissedRuns*cfg.PollInterval + cfg.Lease)
+	return &Relay{repo: repo, pub: pub, cfg: cfg, beat: beat, log: log}
+}
+
+// Check сообщает, удается ли relay забирать события из outbox
+func (r *Relay) Check(ctx context.Context) error {
+	return r.beat.Check(ctx)
+}
+
+// Run публикует события до отмены ctx
+func (r *Relay) Run(ctx context.Context) {
+	r.log.Info("outbox relay started", zap.Duration("poll_interval", r.cfg.PollInterval))
+
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(r.cfg.CleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		// пока outbox полный, забираем следующую пачку без ожидания
+		for {
+			if n := r.relayBatch(ctx); n < r.cfg.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			r.log.Info("outbox relay stopped")
+			return
+		case <-cleanup.C:
+			r.cleanup(ctx)
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayBatch публикует одну пачку и возвращает ее размер
+func (r *Relay) relayBatch(ctx context.Context) int {
+	records, err := r.repo.ClaimPending(ctx, r.cfg.BatchSize, r.cfg.Lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.beat.Fail(err)
+			r.log.Error("claim outbox failed", zap.Error(err))
+		}
+		return 0
+	}
+	r.beat.Beat()
+	if len(records) == 0 {
+		return 0
+	}
+
+	published := make([]int64, 0, len(records))
+	for _, rec := range records {
+		if err := r.pub.Publish(ctx, rec.Event); err != nil {
+			if ctx.Err() != nil {
+				// остановка сервиса: неопубликованные события вернутся по истечении lease
+				break
+			}
+			next := time.Now().UTC().Add(r.backoff(rec.Attempts + 1))
+			r.log.Warn("publish event failed",
+				zap.Int64("outbox_id", rec.ID),
+				zap.String("event_id", rec.Event.ID),
+				zap.String("event_type", string(rec.Event.Type)),
+				zap.Int("attempt", rec.Attempts+1),
+				zap.Time("next_attempt_at", next),
+				zap.Error(err),
+			)
+			if err := r.repo.MarkFailed(ctx, rec.ID, err, next); err != nil {
+				r.log.Error("mark outbox failed", zap.Int64("outbox_id", rec.ID), zap.Error(err))
+			}
+			continue
+		}
+		published = append(published, rec.ID)
+	}
+
+	if len(published) > 0 {
+		// используем не отмененный контекст: события уже опубликованы
+		markCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := r.repo.MarkPublished(markCtx, published); err != nil {
+			// события будут опубликованы повторно по истечении lease
+			r.log.Error("mark outbox published failed", zap.Int("count", len(published)), zap.Error(err))
+		}
+	}
+
+	return len(records)
+}
+
+func (r *Relay) cleanup(ctx context.Context) {
+	before := time.Now().UTC().Add(-r.cfg.Retention)
+	n, err := r.repo.DeletePublished(ctx, before)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.log.Error("outbox cleanup failed", zap.Error(err))
+		}
+		return
+	}
+	if n > 0 {
+		r.log.Info("outbox cleaned up", zap.Int64("count", n), zap.Time("before", before))
+	}
+}
+
+// backoff возвращает экспоненциальную задержку перед попыткой attempt+1
+func (r *Relay) backoff(attempt int) time.Duration {
+	delay := r.cfg.InitialBackoff
+	for i := 1; i < attempt && delay < r.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.cfg.MaxBackoff)
+}
@@ -0,0 +1,312 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"go.uber.org/zap"
+)
+
+// fakeOutbox - outbox в памяти с той же семантикой lease, что и в Postgres
+type fakeOutbox struct {
+	repository.OutboxRepository
+
+	mu          sync.Mutex
+	records     []*outboxRow
+	cleanups    []time.Time
+	markedAfter error // ошибка ctx на момент MarkPublished
+}
+
+type outboxRow struct {
+	rec       models.OutboxRecord
+	next      time.Time
+	published bool
+	lastErr   error
+}
+
+func newFakeOutbox(n int) *fakeOutbox {
+	o := &fakeOutbox{}
+	for i := range n {
+		o.records = append(o.records, &outboxRow{rec: models.OutboxRecord{
+			ID:    int64(i + 1),
+			Event: models.Event{ID: uuid.NewString(), Type: models.EventSubscriptionCreated},
+		}})
+	}
+	return o
+}
+
+func (o *fakeOutbox) ClaimPending(_ context.Context, limit int, lease time.Duration) ([]*models.OutboxRecord, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now().UTC()
+	var claimed []*models.OutboxRecord
+	for _, r := range o.records {
+		if len(claimed) == limit {
+			break
+		}
+		if !r.published && !r.next.After(now) {
+			r.next = now.Add(lease)
+			rec := r.rec
+			claimed = append(claimed, &rec)
+		}
+	}
+	return claimed, nil
+}
+
+func (o *fakeOutbox) MarkPublished(ctx context.Context, ids []int64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.markedAfter = ctx.Err()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	for _, r := range o.records {
+		if slices.Contains(ids, r.rec.ID) {
+			r.published = true
+			r.rec.Attempts++
+		}
+	}
+	return nil
+}
+
+func (o *fakeOutbox) MarkFailed(_ context.Context, id int64, cause error, next time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	r := o.records[id-1]
+	r.rec.Attempts++
+	r.lastErr = cause
+	r.next = next
+	return nil
+}
+
+func (o *fakeOutbox) DeletePublished(_ context.Context, before time.Time) (int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.cleanups = append(o.cleanups, before)
+	return 0, nil
+}
+
+func (o *fakeOutbox) row(id int64) outboxRow {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return *o.records[id-1]
+}
+
+// expire делает отложенное событие доступным для повторной публикации
+func (o *fakeOutbox) expire(id int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.records[id-1].next = time.Time{}
+}
+
+// flakyPublisher не принимает событие первые failures[event.ID] попыток
+type flakyPublisher struct {
+	mu        sync.Mutex
+	failures  map[string]int
+	attempts  []string
+	published []string
+	onPublish func(event models.Event) // вызывается перед публикацией
+}
+
+var errUnavailable = errors.New("broker unavailable")
+
+func (p *flakyPublisher) Publish(_ context.Context, event models.Event) error {
+	if p.onPublish != nil {
+		p.onPublish(event)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempts = append(p.attempts, event.ID)
+	if p.failures[event.ID] > 0 {
+		p.failures[event.ID]--
+		return errUnavailable
+	}
+	p.published = append(p.published, event.ID)
+	return nil
+}
+
+func (p *flakyPublisher) publishedIDs() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.published)
+}
+
+func testRelayConfig() RelayConfig {
+	return RelayConfig{
+		PollInterval:    time.Hour,
+		BatchSize:       10,
+		Lease:           time.Minute,
+		InitialBackoff:  5 * time.Second,
+		MaxBackoff:      10 * time.Minute,
+		Retention:       24 * time.Hour,
+		CleanupInterval: time.Hour,
+	}
+}
+
+func TestRelayRetriesFailedEvent(t *testing.T) {
+	repo := newFakeOutbox(3)
+	failing := repo.records[1].rec.Event.ID
+	pub := &flakyPublisher{failures: map[string]int{failing: 1}}
+	relay := NewRelay(repo, pub, testRelayConfig(), zap.NewNop())
+
+	start := time.Now().UTC()
+	if n := relay.relayBatch(context.Background()); n != 3 {
+		t.Fatalf("relayBatch() = %d, want 3", n)
+	}
+
+	// остальные события публикуются, упавшее откладывается на InitialBackoff
+	for _, id := range []int64{1, 3} {
+		if r := repo.row(id); !r.published || r.rec.Attempts != 1 {
+			t.Errorf("record %d = %+v, want published after one attempt", id, r)
+		}
+	}
+	r := repo.row(2)
+	if r.published || r.rec.Attempts != 1 || !errors.Is(r.lastErr, errUnavailable) {
+		t.Fatalf("record 2 = %+v, want one failed attempt", r)
+	}
+	if wait := r.next.Sub(start); wait < 5*time.Second || wait > 6*time.Second {
+		t.Errorf("record 2 next attempt in %s, want %s", wait, 5*time.Second)
+	}
+
+	// до истечения задержки событие не берется повторно
+	if n := relay.relayBatch(context.Background()); n != 0 {
+		t.Fatalf("relayBatch() before backoff = %d, want 0", n)
+	}
+
+	repo.expire(2)
+	if n := relay.relayBatch(context.Background()); n != 1 {
+		t.Fatalf("relayBatch() after backoff = %d, want 1", n)
+	}
+	if r := repo.row(2); !r.published || r.rec.Attempts != 2 {
+		t.Errorf("record 2 = %+v, want published on the second attempt", r)
+	}
+	if got := pub.attempts; len(got) != 4 || got[1] != failing || got[3] != failing {
+		t.Errorf("publish attempts = %v, want the failed event published twice", got)
+	}
+}
+
+func TestRelayShutdownDuringBatch(t *testing.T) {
+	repo := newFakeOutbox(3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// остановка сервиса приходится на публикацию второго события
+	second := repo.records[1].rec.Event.ID
+	pub := &flakyPublisher{failures: map[string]int{second: 1}, onPublish: func(e models.Event) {
+		if e.ID == second {
+			cancel()
+		}
+	}}
+	relay := NewRelay(repo, pub, testRelayConfig(), zap.NewNop())
+	relay.relayBatch(ctx)
+
+	// опубликованное до остановки отмечается, несмотря на отмененный ctx
+	if r := repo.row(1); !r.published {
+		t.Errorf("record 1 = %+v, want published", r)
+	}
+	if repo.markedAfter != nil {
+		t.Errorf("MarkPublished got canceled context: %v", repo.markedAfter)
+	}
+	// неопубликованные не считаются неудачными и вернутся по истечении lease
+	for _, id := range []int64{2, 3} {
+		if r := repo.row(id); r.published || r.rec.Attempts != 0 || r.lastErr != nil {
+			t.Errorf("record %d = %+v, want untouched", id, r)
+		}
+	}
+}
+
+func TestRelayBackoff(t *testing.T) {
+	relay := NewRelay(nil, nil, testRelayConfig(), zap.NewNop())
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 5 * time.Second},
+		{attempt: 2, want: 10 * time.Second},
+		{attempt: 3, want: 20 * time.Second},
+		{attempt: 7, want: 320 * time.Second},
+		{attempt: 8, want: 10 * time.Minute},
+		{attempt: 100, want: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := relay.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestRelayRun(t *testing.T) {
+	repo := newFakeOutbox(15)
+	flaky := repo.records[0].rec.Event.ID
+	pub := &flakyPublisher{failures: map[string]int{flaky: 2}}
+
+	cfg := testRelayConfig()
+	cfg.PollInterval = 5 * time.Millisecond
+	cfg.CleanupInterval = 5 * time.Millisecond
+	cfg.InitialBackoff = time.Millisecond
+	cfg.MaxBackoff = time.Millisecond
+	relay := NewRelay(repo, pub, cfg, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	// все события доставлены, включая больше одной пачки и упавшее дважды
+	deadline := time.After(5 * time.Second)
+	for len(pub.publishedIDs()) < 15 {
+		select {
+		case <-deadline:
+			t.Fatalf("published %d of 15 events", len(pub.publishedIDs()))
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	if r := repo.row(1); r.rec.Attempts != 3 {
+		t.Errorf("flaky record attempts = %d, want 3", r.rec.Attempts)
+	}
+	if err := relay.Check(ctx); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop after context cancellation")
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if len(repo.cleanups) == 0 {
+		t.Fatal("published events were not cleaned up")
+	}
+	if age := time.Since(repo.cleanups[0]); age < cfg.Retention || age > cfg.Retention+time.Minute {
+		t.Errorf("cleanup before = now - %s, want now - %s", age, cfg.Retention)
+	}
+}
+
+func TestMultiPublisher(t *testing.T) {
+	event := models.Event{ID: "evt-1"}
+	ok := NewMemoryPublisher()
+	failing := &flakyPublisher{failures: map[string]int{"evt-1": 1}}
+
+	err := MultiPublisher{failing, ok}.Publish(context.Background(), event)
+	if !errors.Is(err, errUnavailable) {
+		t.Fatalf("Publish() error = %v, want %v", err, errUnavailable)
+	}
+	// ошибка одного адаптера не мешает публикации в остальные
+	if got := ok.Events(); len(got) != 1 || got[0].ID != "evt-1" {
+		t.Errorf("memory publisher events = %v", got)
+	}
+}
@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/untibullet/subscription-service-em/internal/health"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"go.uber.org/zap"
+)
+
+// RenewalScheduler записывает в outbox subscription.renewing для подписок,
+// которые продлятся первого числа следующего месяца, когда до продления
+// остается не больше lead. Событие для пары (подписка, месяц) записывается один раз
+type RenewalScheduler struct {
+	subs     repository.SubscriptionRepository
+	outbox   repository.OutboxRepository
+	lead     time.Duration
+	interval time.Duration
+	beat     *health.Heartbeat
+	log      *zap.Logger
+}
+
+func NewRenewalScheduler(subs repository.SubscriptionRepository, outbox repository.OutboxRepository, lead, interval time.Duration, log *zap.Logger) *RenewalScheduler {
+	return &RenewalScheduler{subs: subs, outbox: outbox, lead: lead, interval: interval, beat: health.NewHeartbeat(health.MissedRuns * interval), log: log}
+}
+
+// Check сообщает, выполняются ли проверки продлений
+func (s *RenewalScheduler) Check(ctx context.Context) error {
+	return s.beat.Check(ctx)
+}
+
+// Run проверяет продления сразу и затем каждые interval до отмены ctx
+func (s *RenewalScheduler) Run(ctx context.Context) {
+	s.log.Info("renewal scheduler started", zap.Duration("lead", s.lead), zap.Duration("interval", s.interval))
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.check(ctx, time.Now().UTC()); err != nil {
+			if ctx.Err() == nil {
+				s.beat.Fail(err)
+				s.log.Error("renewal check failed", zap.Error(err))
+			}
+		} else {
+			s.beat.Beat()
+		}
+
+		select {
+		case <-ctx.Done():
+			s.log.Info("renewal scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *RenewalScheduler) check(ctx context.Context, now time.Time) error {
+	renewal := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	if renewal.Sub(now) > s.lead {
+		return nil
+	}
+
+	// собираем события отдельно, чтобы не держать соединение выборки во время записи
+	events := make([]models.Event, 0)
+	filter := models.SubscriptionFilter{ActiveOn: &renewal, AllTenants: true}
+	err := s.subs.Stream(ctx, filter, func(sub *models.Subscription) error {
+		// подписка, начинающаяся с этого месяца, не продлевается, а стартует
+		if !sub.StartDate.Before(renewal) {
+			return nil
+		}
+
+		data := models.RenewalData{
+			Subscription: sub,
+			RenewalDate:  renewal.Format("01-2006"),
+		}
+		// детерминированный ID дедуплицирует событие между проверками и экземплярами
+		id := fmt.Sprintf("%s:%s:%s", models.EventSubscriptionRenewing, sub.ID, renewal.Format("2006-01"))
+		event, err := models.NewEvent(id, models.EventSubscriptionRenewing, sub.TenantID, sub.ID, data)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if err := s.outbox.Append(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
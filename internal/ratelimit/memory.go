@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore хранит корзины в памяти процесса. Лимиты действуют в пределах
+// одного экземпляра сервиса
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Take забирает токен из корзины key, предварительно пополнив ее за прошедшее время
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	b.tokens = min(float64(limit.Burst), b.tokens+max(elapsed, 0)*limit.rate())
+	b.updatedAt = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	return limit.result(b.tokens, allowed), nil
+}
+
+// Cleanup удаляет корзины, не использовавшиеся дольше idle
+func (s *MemoryStore) Cleanup(_ context.Context, idle time.Duration) error {
+	threshold := s.now().Add(-idle)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, b := range s.buckets {
+		if b.updatedAt.Before(threshold) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
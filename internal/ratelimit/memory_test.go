@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// clock - время для MemoryStore, которое двигает тест
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time { return c.t }
+
+func newTestStore() (*MemoryStore, *clock) {
+	c := &clock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore()
+	s.now = c.now
+	return s, c
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	// токен в секунду, до трех запросов подряд
+	limit := Limit{Requests: 60, Period: time.Minute, Burst: 3}
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		want    Result
+	}{
+		{name: "full bucket", want: Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Second}},
+		{name: "second in burst", want: Result{Allowed: true, Limit: 3, Remaining: 1, Reset: 2 * time.Second}},
+		{name: "last in burst", want: Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 3 * time.Second}},
+		{name: "empty bucket", want: Result{Allowed: false, Limit: 3, Remaining: 0, Reset: 3 * time.Second, RetryAfter: time.Second}},
+		{name: "half a token", advance: 500 * time.Millisecond, want: Result{Allowed: false, Limit: 3, Remaining: 0, Reset: 2500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}},
+		{name: "token refilled", advance: 500 * time.Millisecond, want: Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 3 * time.Second}},
+		{name: "refill is capped by burst", advance: time.Hour, want: Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Second}},
+		{name: "clock going back does not add tokens", advance: -time.Minute, want: Result{Allowed: true, Limit: 3, Remaining: 1, Reset: 2 * time.Second}},
+	}
+
+	store, c := newTestStore()
+	for _, step := range steps {
+		c.t = c.t.Add(step.advance)
+		got, err := store.Take(context.Background(), "client", limit)
+		if err != nil {
+			t.Fatalf("%s: Take() error = %v", step.name, err)
+		}
+		if got != step.want {
+			t.Fatalf("%s: Take() = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestMemoryStoreKeysAreIndependent(t *testing.T) {
+	limit := Limit{Requests: 1, Period: time.Hour, Burst: 1}
+	store, _ := newTestStore()
+
+	for _, tt := range []struct {
+		key  string
+		want bool
+	}{
+		{key: "a", want: true},
+		{key: "a", want: false},
+		{key: "b", want: true},
+		{key: "b", want: false},
+	} {
+		got, err := store.Take(context.Background(), tt.key, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Allowed != tt.want {
+			t.Fatalf("Take(%q).Allowed = %v, want %v", tt.key, got.Allowed, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreCleanup(t *testing.T) {
+	limit := Limit{Requests: 60, Period: time.Minute, Burst: 3}
+	store, c := newTestStore()
+
+	_, _ = store.Take(context.Background(), "old", limit)
+	c.t = c.t.Add(10 * time.Minute)
+	_, _ = store.Take(context.Background(), "recent", limit)
+
+	if err := store.Cleanup(context.Background(), 5*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.buckets["old"]; ok {
+		t.Error("idle bucket was not removed")
+	}
+	if _, ok := store.buckets["recent"]; !ok {
+		t.Error("recent bucket was removed")
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	store, _ := newTestStore()
+	limiter := NewLimiter(store, map[Group]Limit{
+		GroupWrite: {Requests: 60, Period: time.Minute, Burst: 1},
+	}, time.Minute, zap.NewNop())
+
+	tests := []struct {
+		name   string
+		group  Group
+		client string
+		want   bool
+	}{
+		{name: "first write", group: GroupWrite, client: "user-1", want: true},
+		{name: "second write", group: GroupWrite, client: "user-1", want: false},
+		{name: "another client", group: GroupWrite, client: "user-2", want: true},
+		{name: "group without limit", group: GroupRead, client: "user-1", want: true},
+	}
+	for _, tt := range tests {
+		got, err := limiter.Allow(context.Background(), tt.group, tt.client)
+		if err != nil {
+			t.Fatalf("%s: Allow() error = %v", tt.name, err)
+		}
+		if got.Allowed != tt.want {
+			t.Fatalf("%s: Allow().Allowed = %v, want %v", tt.name, got.Allowed, tt.want)
+		}
+	}
+
+	if got := limiter.Policy(GroupWrite); got != "1;w=1" {
+		t.Errorf("Policy() = %q, want %q", got, "1;w=1")
+	}
+
+	// выключение лимитов без перезапуска
+	limiter.SetLimits(nil)
+	if got, _ := limiter.Allow(context.Background(), GroupWrite, "user-1"); !got.Allowed || got.Limit != 0 {
+		t.Errorf("Allow() without limits = %+v, want allowed without limit", got)
+	}
+}
@@ -0,0 +1,33 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/untibullet/subscription-service-em/internal/repository"
+)
+
+// PostgresStore хранит корзины в Postgres, поэтому лимиты общие для всех
+// экземпляров сервиса
+type PostgresStore struct {
+	repo repository.RateLimitRepository
+}
+
+func NewPostgresStore(repo repository.RateLimitRepository) *PostgresStore {
+	return &PostgresStore{repo: repo}
+}
+
+// Take забирает токен из корзины key одним запросом к БД
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	tokens, allowed, err := s.repo.Take(ctx, key, float64(limit.Burst), limit.rate())
+	if err != nil {
+		return Result{}, err
+	}
+	return limit.result(tokens, allowed), nil
+}
+
+// Cleanup удаляет корзины, не использовавшиеся дольше idle
+func (s *PostgresStore) Cleanup(ctx context.Context, idle time.Duration) error {
+	_, err := s.repo.DeleteIdle(ctx, idle)
+	return err
+}
@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Group - группа маршрутов с общим лимитом
+type Group string
+
+const (
+	GroupRead    Group = "read"
+	GroupWrite   Group = "write"
+	GroupReports Group = "reports"
+	GroupIP      Group = "ip" // все запросы с IP-адреса, до аутентификации
+)
+
+// Limit - корзина токенов: вмещает Burst запросов и пополняется на Requests
+// запросов за Period
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// rate - скорость пополнения корзины, токенов в секунду
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// refill - время, за которое пустая корзина наполняется полностью
+func (l Limit) refill() time.Duration {
+	return time.Duration(float64(l.Burst) / l.rate() * float64(time.Second))
+}
+
+// Result - итог проверки запроса
+type Result struct {
+	Allowed    bool
+	Limit      int           // емкость корзины
+	Remaining  int           // сколько запросов можно выполнить сразу
+	Reset      time.Duration // через сколько корзина наполнится полностью
+	RetryAfter time.Duration // через сколько появится токен; 0, если запрос пропущен
+}
+
+// result строит Result по числу токенов, оставшихся в корзине после проверки
+func (l Limit) result(tokens float64, allowed bool) Result {
+	tokens = max(tokens, 0)
+	res := Result{
+		Allowed:   allowed,
+		Limit:     l.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     l.until(float64(l.Burst) - tokens),
+	}
+	if !allowed {
+		res.RetryAfter = l.until(1 - tokens)
+	}
+	return res
+}
+
+// until - время, за которое в корзину добавится tokens токенов
+func (l Limit) until(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(tokens / l.rate() * float64(time.Second)))
+}
+
+// Store хранит корзины токенов
+type Store interface {
+	// Take забирает токен из корзины key, если он есть
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// Cleanup удаляет корзины, не использовавшиеся дольше idle
+	Cleanup(ctx context.Context, idle time.Duration) error
+}
+
+// Limiter ограничивает частоту запросов клиентов по группам маршрутов
+type Limiter struct {
+	store           Store
+	cleanupInterval time.Duration
+	log             *zap.Logger
+
+	mu     sync.RWMutex
+	limits map[Group]Limit
+}
+
+func NewLimiter(store Store, limits map[Group]Limit, cleanupInterval time.Duration, log *zap.Logger) *Limiter {
+	return &Limiter{store: store, limits: limits, cleanupInterval: cleanupInterval, log: log}
+}
+
+// SetLimits заменяет лимиты групп. Пустые limits выключают ограничение.
+// Накопленные корзины сохраняются и пересчитываются по новому лимиту
+func (l *Limiter) SetLimits(limits map[Group]Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+}
+
+func (l *Limiter) limit(group Group) (Limit, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	limit, ok := l.limits[group]
+	return limit, ok
+}
+
+// Allow проверяет запрос клиента client к группе group. Запросы к группам
+// без лимита пропускаются (Result.Limit равен 0)
+func (l *Limiter) Allow(ctx context.Context, group Group, client string) (Result, error) {
+	limit, ok := l.limit(group)
+	if !ok {
+		return Result{Allowed: true}, nil
+	}
+	return l.store.Take(ctx, string(group)+":"+client, limit)
+}
+
+// Policy возвращает лимит группы в формате заголовка RateLimit-Policy:
+// емкость корзины и время ее полного наполнения в секундах
+func (l *Limiter) Policy(group Group) string {
+	limit, ok := l.limit(group)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%d;w=%d", limit.Burst, int(math.Ceil(limit.refill().Seconds())))
+}
+
+// idle - время наполнения самой медленной корзины
+func (l *Limiter) idle() time.Duration {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var idle time.Duration
+	for _, limit := range l.limits {
+		idle = max(idle, limit.refill())
+	}
+	return idle
+}
+
+// Run удаляет неиспользуемые корзины до отмены ctx. Корзина, простоявшая
+// дольше времени наполнения, полна, и ее удаление не меняет лимит
+func (l *Limiter) Run(ctx context.Context) {
+	l.log.Info("rate limit cleanup started", zap.Duration("interval", l.cleanupInterval))
+
+	ticker := time.NewTicker(l.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			l.log.Info("rate limit cleanup stopped")
+			return
+		case <-ticker.C:
+			if err := l.store.Cleanup(ctx, l.idle()); err != nil && ctx.Err() == nil {
+				l.log.Error("rate limit cleanup failed", zap.Error(err))
+			}
+		}
+	}
+}
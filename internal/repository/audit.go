@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+)
+
+// auditIgnoredFields не попадают в diff: меняются при любом изменении
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// writeAudit записывает изменение подписки в журнал в той же транзакции,
+// что и само изменение. before и after могут быть nil (создание/удаление)
+func writeAudit(ctx context.Context, tx pgx.Tx, action models.AuditAction, id uuid.UUID, before, after *models.Subscription) error {
+	diff, err := diffSubscriptions(before, after)
+	if err != nil {
+		return fmt.Errorf("failed to build audit diff: %w", err)
+	}
+
+	var requestID *string
+	if v := reqctx.RequestID(ctx); v != "" {
+		requestID = &v
+	}
+
+	query := `
+		INSERT INTO subscription_audit (subscription_id, tenant_id, action, actor, request_id, diff, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err = tx.Exec(ctx, annotate(ctx, query),
+		id,
+		reqctx.Tenant(ctx),
+		action,
+		reqctx.Actor(ctx),
+		requestID,
+		diff,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write audit: %w", err)
+	}
+
+	return nil
+}
+
+// diffSubscriptions возвращает изменившиеся поля в терминах JSON-представления подписки
+func diffSubscriptions(before, after *models.Subscription) (map[string]models.FieldChange, error) {
+	b, err := subscriptionFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := subscriptionFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := make(map[string]models.FieldChange)
+	for k, v := range b {
+		if !auditIgnoredFields[k] && !reflect.DeepEqual(v, a[k]) {
+			diff[k] = models.FieldChange{Before: v, After: a[k]}
+		}
+	}
+	for k, v := range a {
+		if _, seen := b[k]; !seen && !auditIgnoredFields[k] {
+			diff[k] = models.FieldChange{Before: nil, After: v}
+		}
+	}
+
+	return diff, nil
+}
+
+func subscriptionFields(sub *models.Subscription) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if sub == nil {
+		return fields, nil
+	}
+
+	raw, err := json.Marshal(sub)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// ListAudit возвращает записи журнала изменений организации запроса, новые первыми
+func (r *PostgresSubscriptionRepo) ListAudit(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEntry, error) {
+	ctx, span := startSpan(ctx, "PostgresSubscriptionRepo.ListAudit")
+	defer span.End()
+
+	var query strings.Builder
+	query.WriteString(`
+		SELECT id, subscription_id, action, actor, COALESCE(request_id, ''), diff, created_at
+		FROM subscription_audit
+		WHERE tenant_id = $1
+	`)
+
+	args := []interface{}{reqctx.Tenant(ctx)}
+	argPos := 2
+
+	if filter.SubscriptionID != nil {
+		query.WriteString(fmt.Sprintf(" AND subscription_id = $%d", argPos))
+		args = append(args, *filter.SubscriptionID)
+		argPos++
+	}
+
+	if filter.Actor != nil {
+		query.WriteString(fmt.Sprintf(" AND actor = $%d", argPos))
+		args = append(args, *filter.Actor)
+		argPos++
+	}
+
+	if filter.Action != nil {
+		query.WriteString(fmt.Sprintf(" AND action = $%d", argPos))
+		args = append(args, *filter.Action)
+		argPos++
+	}
+
+	if filter.From != nil {
+		query.WriteString(fmt.Sprintf(" AND created_at >= $%d", argPos))
+		args = append(args, filter.From.UTC())
+		argPos++
+	}
+
+	if filter.To != nil {
+		query.WriteString(fmt.Sprintf(" AND created_at < $%d", argPos))
+		args = append(args, filter.To.UTC())
+		argPos++
+	}
+
+	query.WriteString(" ORDER BY created_at DESC, id DESC")
+
+	if filter.Limit > 0 {
+		query.WriteString(fmt.Sprintf(" LIMIT $%d", argPos))
+		args = append(args, filter.Limit)
+		argPos++
+	}
+
+	if filter.Offset > 0 {
+		query.WriteString(fmt.Sprintf(" OFFSET $%d", argPos))
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := r.pool.Query(ctx, annotate(ctx, query.String()), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*models.AuditEntry, 0)
+	for rows.Next() {
+		var e models.AuditEntry
+		err := rows.Scan(
+			&e.ID,
+			&e.SubscriptionID,
+			&e.Action,
+			&e.Actor,
+			&e.RequestID,
+			&e.Diff,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, &e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return entries, nil
+}
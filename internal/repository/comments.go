@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+)
+
+// queryComments - добавлять ли к SQL комментарий с идентификатором запроса
+var queryComments atomic.Bool
+
+// EnableQueryComments добавляет к запросам, выполняемым в рамках HTTP-запроса,
+// комментарий /* request_id=... */, который виден в pg_stat_activity и логах
+// Postgres. Текст запроса становится уникальным, поэтому запросы выполняются
+// через безымянные подготовленные операторы, не засоряя кэш pgx
+func EnableQueryComments(cfg *pgxpool.Config) {
+	cfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeDescribeExec
+	queryComments.Store(true)
+}
+
+// annotate добавляет к запросу комментарий с идентификатором запроса из ctx.
+// Идентификатор проверяется при приеме (service.RequestContext), но
+// идентификатор, способный закрыть комментарий, все равно пропускается
+func annotate(ctx context.Context, query string) string {
+	if !queryComments.Load() {
+		return query
+	}
+	id := reqctx.RequestID(ctx)
+	if id == "" || strings.Contains(id, "*/") {
+		return query
+	}
+	return "/* request_id=" + id + " */ " + query
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyExists   = errors.New("api key with this name already exists")
+)
+
+const apiKeyColumns = `id, tenant_id, name, prefix, key_hash, scopes, user_id, expires_at, last_used_at, revoked_at, created_at, updated_at`
+
+// apiKeyTouchInterval - не чаще этого обновляем last_used_at, чтобы не писать в БД на каждый запрос
+const apiKeyTouchInterval = time.Minute
+
+type PostgresAPIKeyRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresAPIKeyRepo(pool *pgxpool.Pool) *PostgresAPIKeyRepo {
+	return &PostgresAPIKeyRepo{pool: pool}
+}
+
+// Create сохраняет ключ организации запроса
+func (r *PostgresAPIKeyRepo) Create(ctx context.Context, key *models.APIKey) error {
+	query := `
+		INSERT INTO api_keys (id, tenant_id, name, prefix, key_hash, scopes, user_id, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	key.TenantID = reqctx.Tenant(ctx)
+
+	_, err := r.pool.Exec(ctx, annotate(ctx, query),
+		key.ID,
+		key.TenantID,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		key.Scopes,
+		key.UserID,
+		key.ExpiresAt,
+		key.CreatedAt,
+		key.UpdatedAt,
+	)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrAPIKeyExists
+		}
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+// GetByPrefix возвращает ключ по префиксу для проверки предъявленного ключа.
+// Организация еще не известна, поэтому поиск идет по всем организациям
+func (r *PostgresAPIKeyRepo) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1`
+
+	key, err := scanAPIKey(r.pool.QueryRow(ctx, annotate(ctx, query), prefix))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// List возвращает все ключи организации, включая отозванные
+func (r *PostgresAPIKeyRepo) List(ctx context.Context) ([]*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE tenant_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.pool.Query(ctx, annotate(ctx, query), reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*models.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Rotate заменяет секрет действующего ключа, сохраняя его права и срок действия
+func (r *PostgresAPIKeyRepo) Rotate(ctx context.Context, id uuid.UUID, prefix string, hash []byte) (*models.APIKey, error) {
+	query := `
+		UPDATE api_keys
+		SET prefix = $3, key_hash = $4, updated_at = $5
+		WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL
+		RETURNING ` + apiKeyColumns
+
+	key, err := scanAPIKey(r.pool.QueryRow(ctx, annotate(ctx, query), id, reqctx.Tenant(ctx), prefix, hash, time.Now().UTC()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to rotate api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Revoke отзывает ключ. Запись остается для истории
+func (r *PostgresAPIKeyRepo) Revoke(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE api_keys SET revoked_at = $3, updated_at = $3 WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL`
+
+	result, err := r.pool.Exec(ctx, annotate(ctx, query), id, reqctx.Tenant(ctx), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchLastUsed отмечает использование ключа
+func (r *PostgresAPIKeyRepo) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := `
+		UPDATE api_keys SET last_used_at = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $3)
+	`
+
+	if _, err := r.pool.Exec(ctx, annotate(ctx, query), id, at, at.Add(-apiKeyTouchInterval)); err != nil {
+		return fmt.Errorf("failed to touch api key: %w", err)
+	}
+
+	return nil
+}
+
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.TenantID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		&key.Scopes,
+		&key.UserID,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
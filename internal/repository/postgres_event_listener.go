@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/untibullet/subscription-service-em/internal/models"
+)
+
+// EventsChannel - канал LISTEN/NOTIFY, в который пишутся события изменения подписок
+const EventsChannel = "subscription_events"
+
+type PostgresEventListener struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresEventListener(pool *pgxpool.Pool) *PostgresEventListener {
+	return &PostgresEventListener{pool: pool}
+}
+
+// Listen занимает соединение из пула, подписывается на EventsChannel и вызывает
+// fn для каждого события, пока соединение не оборвется или не отменится ctx.
+// Нераспознанные уведомления пропускаются
+func (l *PostgresEventListener) Listen(ctx context.Context, fn func(models.StreamEvent)) error {
+	pooled, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	// соединение с активным LISTEN нельзя возвращать в пул
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+EventsChannel); err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+
+		var event models.StreamEvent
+		if err := json.Unmarshal([]byte(n.Payload), &event); err != nil {
+			continue
+		}
+		fn(event)
+	}
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresHealthRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresHealthRepo(pool *pgxpool.Pool) *PostgresHealthRepo {
+	return &PostgresHealthRepo{pool: pool}
+}
+
+// Ping проверяет соединение с БД
+func (r *PostgresHealthRepo) Ping(ctx context.Context) error {
+	if err := r.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
+// SchemaVersion возвращает версию последней примененной миграции goose
+func (r *PostgresHealthRepo) SchemaVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := r.pool.QueryRow(ctx, `SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return version, nil
+}
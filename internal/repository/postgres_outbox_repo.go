@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/untibullet/subscription-service-em/internal/models"
+)
+
+// querier - общий интерфейс пула и транзакции для записи в outbox
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+type PostgresOutboxRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresOutboxRepo(pool *pgxpool.Pool) *PostgresOutboxRepo {
+	return &PostgresOutboxRepo{pool: pool}
+}
+
+// writeSubscriptionEvent записывает событие изменения подписки в outbox
+// в той же транзакции, что и само изменение, и оповещает слушателей
+// EventsChannel. Уведомление доставляется только после фиксации транзакции.
+// Без outbox событие только получает номер из последовательности outbox,
+// чтобы поток событий не зависел от публикации
+func writeSubscriptionEvent(ctx context.Context, tx pgx.Tx, outbox bool, t models.EventType, sub *models.Subscription) error {
+	event, err := models.NewEvent(uuid.NewString(), t, sub.TenantID, sub.ID, sub)
+	if err != nil {
+		return fmt.Errorf("failed to build event: %w", err)
+	}
+
+	var seq int64
+	if outbox {
+		seq, err = insertOutbox(ctx, tx, event)
+	} else {
+		seq, err = nextOutboxSeq(ctx, tx)
+	}
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(models.StreamEvent{Seq: seq, Event: event})
+	if err != nil {
+		return fmt.Errorf("failed to build notification: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, annotate(ctx, `SELECT pg_notify($1, $2)`), EventsChannel, string(payload)); err != nil {
+		return fmt.Errorf("failed to notify listeners: %w", err)
+	}
+
+	return nil
+}
+
+// nextOutboxSeq выдает номер события без записи в outbox
+func nextOutboxSeq(ctx context.Context, db querier) (int64, error) {
+	var seq int64
+	if err := db.QueryRow(ctx, annotate(ctx, `SELECT nextval(pg_get_serial_sequence('outbox', 'id'))`)).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("failed to get event seq: %w", err)
+	}
+	return seq, nil
+}
+
+// insertOutbox добавляет событие в outbox и возвращает его порядковый номер.
+// Событие с уже известным ID игнорируется, в этом случае возвращается 0
+func insertOutbox(ctx context.Context, db querier, event models.Event) (int64, error) {
+	query := `
+		INSERT INTO outbox (event_id, event_type, tenant_id, subscription_id, payload, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		ON CONFLICT (event_id) DO NOTHING
+		RETURNING id
+	`
+
+	var seq int64
+	err := db.QueryRow(ctx, annotate(ctx, query), event.ID, event.Type, event.TenantID, event.SubscriptionID, event, time.Now().UTC()).Scan(&seq)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("failed to write outbox: %w", err)
+	}
+
+	return seq, nil
+}
+
+// Append добавляет событие в outbox вне транзакции изменения (для событий,
+// не связанных с записью подписки, например о предстоящем продлении)
+func (r *PostgresOutboxRepo) Append(ctx context.Context, event models.Event) error {
+	_, err := insertOutbox(ctx, r.pool, event)
+	return err
+}
+
+// ClaimPending выбирает до limit неопубликованных событий в порядке записи
+// и откладывает их на lease, чтобы их не взял другой экземпляр сервиса
+func (r *PostgresOutboxRepo) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxRecord, error) {
+	query := `
+		UPDATE outbox
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE published_at IS NULL AND next_attempt_at <= $1
+			ORDER BY id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, payload, attempts
+	`
+
+	now := time.Now().UTC()
+	rows, err := r.pool.Query(ctx, annotate(ctx, query), now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]*models.OutboxRecord, 0)
+	for rows.Next() {
+		var rec models.OutboxRecord
+		if err := rows.Scan(&rec.ID, &rec.Event, &rec.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox record: %w", err)
+		}
+		records = append(records, &rec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	// RETURNING не гарантирует порядок
+	slices.SortFunc(records, func(a, b *models.OutboxRecord) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return records, nil
+}
+
+// MarkPublished отмечает события опубликованными
+func (r *PostgresOutboxRepo) MarkPublished(ctx context.Context, ids []int64) error {
+	query := `UPDATE outbox SET published_at = $2, attempts = attempts + 1, last_error = NULL WHERE id = ANY($1)`
+
+	if _, err := r.pool.Exec(ctx, annotate(ctx, query), ids, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to mark outbox published: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed сохраняет ошибку публикации и время следующей попытки
+func (r *PostgresOutboxRepo) MarkFailed(ctx context.Context, id int64, cause error, next time.Time) error {
+	query := `UPDATE outbox SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE id = $1`
+
+	if _, err := r.pool.Exec(ctx, annotate(ctx, query), id, cause.Error(), next); err != nil {
+		return fmt.Errorf("failed to mark outbox failed: %w", err)
+	}
+
+	return nil
+}
+
+// DeletePublished удаляет события, опубликованные раньше before
+func (r *PostgresOutboxRepo) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM outbox WHERE published_at IS NOT NULL AND published_at < $1`
+
+	result, err := r.pool.Exec(ctx, annotate(ctx, query), before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean up outbox: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/untibullet/subscription-service-em/internal/models"
+)
+
+// fakeTx записывает запросы транзакции изменения подписки. Остальные методы
+// pgx.Tx не вызываются
+type fakeTx struct {
+	pgx.Tx
+	seq       int64
+	insertErr error
+	queries   []string
+	notified  string
+}
+
+func (tx *fakeTx) QueryRow(_ context.Context, sql string, _ ...interface{}) pgx.Row {
+	tx.queries = append(tx.queries, sql)
+	if strings.Contains(sql, "INSERT INTO outbox") && tx.insertErr != nil {
+		return fakeRow{err: tx.insertErr}
+	}
+	return fakeRow{seq: tx.seq}
+}
+
+func (tx *fakeTx) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	tx.queries = append(tx.queries, sql)
+	if strings.Contains(sql, "pg_notify") {
+		tx.notified = args[1].(string)
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+type fakeRow struct {
+	seq int64
+	err error
+}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*int64) = r.seq
+	return nil
+}
+
+func TestWriteSubscriptionEvent(t *testing.T) {
+	errInsert := errors.New("outbox is full")
+
+	tests := []struct {
+		name        string
+		outbox      bool
+		insertErr   error
+		wantQueries []string // подстроки запросов в порядке выполнения
+		wantErr     error
+	}{
+		{
+			name:        "event is written to outbox in the change transaction",
+			outbox:      true,
+			wantQueries: []string{"INSERT INTO outbox", "pg_notify"},
+		},
+		{
+			name:        "outbox disabled",
+			outbox:      false,
+			wantQueries: []string{"nextval(pg_get_serial_sequence('outbox', 'id'))", "pg_notify"},
+		},
+		{
+			// ошибка откатывает транзакцию вместе с изменением подписки
+			name:        "outbox error aborts the change",
+			outbox:      true,
+			insertErr:   errInsert,
+			wantQueries: []string{"INSERT INTO outbox"},
+			wantErr:     errInsert,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &fakeTx{seq: 42, insertErr: tt.insertErr}
+			sub := &models.Subscription{ID: uuid.New(), TenantID: "acme", ServiceName: "Netflix", Price: 400}
+
+			err := writeSubscriptionEvent(context.Background(), tx, tt.outbox, models.EventSubscriptionCreated, sub)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("writeSubscriptionEvent() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(tx.queries) != len(tt.wantQueries) {
+				t.Fatalf("queries = %q, want %q", tx.queries, tt.wantQueries)
+			}
+			for i, want := range tt.wantQueries {
+				if !strings.Contains(tx.queries[i], want) {
+					t.Errorf("query %d = %q, want %q", i, tx.queries[i], want)
+				}
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			var event models.StreamEvent
+			if err := json.Unmarshal([]byte(tx.notified), &event); err != nil {
+				t.Fatalf("invalid notification %q: %v", tx.notified, err)
+			}
+			if event.Seq != 42 || event.Event.SubscriptionID != sub.ID || event.Event.TenantID != "acme" || event.Event.Type != models.EventSubscriptionCreated {
+				t.Errorf("notification = %+v", event)
+			}
+		})
+	}
+}
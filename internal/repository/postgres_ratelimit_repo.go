@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresRateLimitRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRateLimitRepo(pool *pgxpool.Pool) *PostgresRateLimitRepo {
+	return &PostgresRateLimitRepo{pool: pool}
+}
+
+// Take пополняет корзину key со скоростью rate токенов в секунду, но не выше
+// capacity, и забирает из нее токен, если он есть. Возвращает оставшееся число
+// токенов и признак того, что токен был взят. Время берется из БД, чтобы
+// расхождение часов экземпляров сервиса не влияло на лимит
+func (r *PostgresRateLimitRepo) Take(ctx context.Context, key string, capacity, rate float64) (float64, bool, error) {
+	// в DO UPDATE все выражения видят строку до изменения, поэтому
+	// пополненная корзина вычисляется одинаково для tokens и allowed
+	query := `
+		INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+		VALUES ($1, $2::float8 - 1, true, now())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * $3::float8)
+				- CASE WHEN LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * $3::float8) >= 1
+					THEN 1 ELSE 0 END,
+			allowed = LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * $3::float8) >= 1,
+			updated_at = now()
+		RETURNING tokens, allowed
+	`
+
+	var (
+		tokens  float64
+		allowed bool
+	)
+	if err := r.pool.QueryRow(ctx, annotate(ctx, query), key, capacity, rate).Scan(&tokens, &allowed); err != nil {
+		return 0, false, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	return tokens, allowed, nil
+}
+
+// DeleteIdle удаляет корзины, не использовавшиеся дольше idle
+func (r *PostgresRateLimitRepo) DeleteIdle(ctx context.Context, idle time.Duration) (int64, error) {
+	query := `DELETE FROM rate_limit_buckets WHERE updated_at < now() - make_interval(secs => $1)`
+
+	result, err := r.pool.Exec(ctx, annotate(ctx, query), idle.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete idle rate limit buckets: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
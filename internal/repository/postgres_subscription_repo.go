@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
 )
 
 var (
@@ -17,59 +19,76 @@ var (
 	ErrAlreadyExists = errors.New("subscription already exists")
 )
 
+// subscriptionColumns - порядок колонок, ожидаемый scanSubscription
+const subscriptionColumns = `id, tenant_id, service_name, price, user_id, start_date, end_date, created_at, updated_at, deleted_at`
+
+// PostgresSubscriptionRepo выполняет List, Stream, CalculateCost и Stats на
+// репликах из replicas, остальные запросы - на основной БД. replicas может
+// быть nil, тогда все запросы выполняются на основной БД. Изменения пишутся
+// в outbox, только если outbox включен: иначе события некому публиковать и удалять
 type PostgresSubscriptionRepo struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	replicas *ReplicaSet
+	outbox   bool
 }
 
-func NewPostgresSubscriptionRepo(pool *pgxpool.Pool) *PostgresSubscriptionRepo {
-	return &PostgresSubscriptionRepo{pool: pool}
+func NewPostgresSubscriptionRepo(pool *pgxpool.Pool, replicas *ReplicaSet, outbox bool) *PostgresSubscriptionRepo {
+	return &PostgresSubscriptionRepo{pool: pool, replicas: replicas, outbox: outbox}
 }
 
-// Create создает новую подписку
+// Create создает новую подписку в организации запроса
 func (r *PostgresSubscriptionRepo) Create(ctx context.Context, sub *models.Subscription) error {
+	ctx, span := startSpan(ctx, "PostgresSubscriptionRepo.Create")
+	defer span.End()
+
 	query := `
-		INSERT INTO subscriptions (id, service_name, price, user_id, start_date, end_date, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO subscriptions (id, tenant_id, service_name, price, user_id, start_date, end_date, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 
-	_, err := r.pool.Exec(ctx, query,
-		sub.ID,
-		sub.ServiceName,
-		sub.Price,
-		sub.UserID,
-		sub.StartDate,
-		sub.EndDate,
-		sub.CreatedAt,
-		sub.UpdatedAt,
-	)
+	sub.TenantID = reqctx.Tenant(ctx)
+
+	return r.withTx(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, annotate(ctx, query),
+			sub.ID,
+			sub.TenantID,
+			sub.ServiceName,
+			sub.Price,
+			sub.UserID,
+			sub.StartDate,
+			sub.EndDate,
+			sub.CreatedAt,
+			sub.UpdatedAt,
+		)
 
-	if err != nil {
-		return fmt.Errorf("failed to create subscription: %w", err)
-	}
+		if err != nil {
+			return fmt.Errorf("failed to create subscription: %w", err)
+		}
+
+		if err := writeAudit(ctx, tx, models.AuditCreate, sub.ID, nil, sub); err != nil {
+			return err
+		}
 
-	return nil
+		return writeSubscriptionEvent(ctx, tx, r.outbox, models.EventSubscriptionCreated, sub)
+	})
 }
 
-// GetByID возвращает подписку по ID
-func (r *PostgresSubscriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+// GetByID возвращает подписку по ID. Удаленные подписки возвращаются
+// только с opts.IncludeDeleted
+func (r *PostgresSubscriptionRepo) GetByID(ctx context.Context, id uuid.UUID, opts models.GetOptions) (*models.Subscription, error) {
+	ctx, span := startSpan(ctx, "PostgresSubscriptionRepo.GetByID")
+	defer span.End()
+
 	query := `
-		SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at
+		SELECT ` + subscriptionColumns + `
 		FROM subscriptions
-		WHERE id = $1
+		WHERE id = $1 AND tenant_id = $2
 	`
+	if !opts.IncludeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
 
-	var sub models.Subscription
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&sub.ID,
-		&sub.ServiceName,
-		&sub.Price,
-		&sub.UserID,
-		&sub.StartDate,
-		&sub.EndDate,
-		&sub.CreatedAt,
-		&sub.UpdatedAt,
-	)
-
+	sub, err := scanSubscription(r.pool.QueryRow(ctx, annotate(ctx, query), id, reqctx.Tenant(ctx)))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -77,58 +96,241 @@ func (r *PostgresSubscriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (*
 		return nil, fmt.Errorf("failed to get subscription: %w", err)
 	}
 
-	return &sub, nil
+	return sub, nil
 }
 
 // Update обновляет подписку
 func (r *PostgresSubscriptionRepo) Update(ctx context.Context, sub *models.Subscription) error {
+	ctx, span := startSpan(ctx, "PostgresSubscriptionRepo.Update")
+	defer span.End()
+
 	query := `
 		UPDATE subscriptions
 		SET service_name = $2, price = $3, start_date = $4, end_date = $5, updated_at = $6
 		WHERE id = $1
-	`
+		RETURNING ` + subscriptionColumns
 
-	result, err := r.pool.Exec(ctx, query,
-		sub.ID,
-		sub.ServiceName,
-		sub.Price,
-		sub.StartDate,
-		sub.EndDate,
-		sub.UpdatedAt,
-	)
+	return r.withTx(ctx, func(tx pgx.Tx) error {
+		before, err := lockSubscription(ctx, tx, sub.ID, false)
+		if err != nil {
+			return err
+		}
+
+		after, err := scanSubscription(tx.QueryRow(ctx, annotate(ctx, query),
+			sub.ID,
+			sub.ServiceName,
+			sub.Price,
+			sub.StartDate,
+			sub.EndDate,
+			sub.UpdatedAt,
+		))
+		if err != nil {
+			return fmt.Errorf("failed to update subscription: %w", err)
+		}
+
+		if err := writeAudit(ctx, tx, models.AuditUpdate, sub.ID, before, after); err != nil {
+			return err
+		}
+
+		return writeSubscriptionEvent(ctx, tx, r.outbox, models.EventSubscriptionUpdated, after)
+	})
+}
+
+// Delete помечает подписку удаленной и возвращает ее итоговое состояние.
+// Физически строка удаляется позже, в PurgeDeleted по истечении срока хранения
+func (r *PostgresSubscriptionRepo) Delete(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	ctx, span := startSpan(ctx, "PostgresSubscriptionRepo.Delete")
+	defer span.End()
 
+	query := `UPDATE subscriptions SET deleted_at = $2 WHERE id = $1 RETURNING ` + subscriptionColumns
+
+	var deleted *models.Subscription
+	err := r.withTx(ctx, func(tx pgx.Tx) error {
+		before, err := lockSubscription(ctx, tx, id, false)
+		if err != nil {
+			return err
+		}
+
+		after, err := scanSubscription(tx.QueryRow(ctx, annotate(ctx, query), id, time.Now().UTC()))
+		if err != nil {
+			return fmt.Errorf("failed to delete subscription: %w", err)
+		}
+		deleted = after
+
+		if err := writeAudit(ctx, tx, models.AuditDelete, id, before, after); err != nil {
+			return err
+		}
+
+		return writeSubscriptionEvent(ctx, tx, r.outbox, models.EventSubscriptionDeleted, after)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update subscription: %w", err)
+		return nil, err
 	}
 
-	if result.RowsAffected() == 0 {
-		return ErrNotFound
+	return deleted, nil
+}
+
+// Restore снимает пометку об удалении
+func (r *PostgresSubscriptionRepo) Restore(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	ctx, span := startSpan(ctx, "PostgresSubscriptionRepo.Restore")
+	defer span.End()
+
+	query := `
+		UPDATE subscriptions
+		SET deleted_at = NULL, updated_at = $2
+		WHERE id = $1
+		RETURNING ` + subscriptionColumns
+
+	var restored *models.Subscription
+	err := r.withTx(ctx, func(tx pgx.Tx) error {
+		before, err := lockSubscription(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+
+		after, err := scanSubscription(tx.QueryRow(ctx, annotate(ctx, query), id, time.Now().UTC()))
+		if err != nil {
+			return fmt.Errorf("failed to restore subscription: %w", err)
+		}
+		restored = after
+
+		if err := writeAudit(ctx, tx, models.AuditRestore, id, before, after); err != nil {
+			return err
+		}
+
+		// для потребителей восстановление - это изменение подписки
+		return writeSubscriptionEvent(ctx, tx, r.outbox, models.EventSubscriptionUpdated, after)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return restored, nil
 }
 
-// Delete удаляет подписку
-func (r *PostgresSubscriptionRepo) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM subscriptions WHERE id = $1`
+// PurgeDeleted безвозвратно удаляет подписки всех организаций, помеченные
+// удаленными раньше before. Каждое удаление фиксируется в журнале изменений
+func (r *PostgresSubscriptionRepo) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "PostgresSubscriptionRepo.PurgeDeleted")
+	defer span.End()
+
+	query := `
+		WITH purged AS (
+			DELETE FROM subscriptions
+			WHERE deleted_at IS NOT NULL AND deleted_at < $1
+			RETURNING id, tenant_id
+		)
+		INSERT INTO subscription_audit (subscription_id, tenant_id, action, actor, diff, created_at)
+		SELECT id, tenant_id, $2, $3, '{}'::jsonb, $4 FROM purged
+	`
 
-	result, err := r.pool.Exec(ctx, query, id)
+	result, err := r.pool.Exec(ctx, annotate(ctx, query), before, models.AuditPurge, reqctx.Actor(ctx), time.Now().UTC())
 	if err != nil {
-		return fmt.Errorf("failed to delete subscription: %w", err)
+		return 0, fmt.Errorf("failed to purge subscriptions: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// lockSubscription читает подписку организации запроса с блокировкой строки
+// до конца транзакции. deleted определяет, ищется ли удаленная или действующая подписка
+func lockSubscription(ctx context.Context, tx pgx.Tx, id uuid.UUID, deleted bool) (*models.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE id = $1 AND tenant_id = $2`
+	if deleted {
+		query += " AND deleted_at IS NOT NULL"
+	} else {
+		query += " AND deleted_at IS NULL"
 	}
+	query += " FOR UPDATE"
 
-	if result.RowsAffected() == 0 {
-		return ErrNotFound
+	sub, err := scanSubscription(tx.QueryRow(ctx, annotate(ctx, query), id, reqctx.Tenant(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock subscription: %w", err)
 	}
 
-	return nil
+	return sub, nil
+}
+
+// withTx выполняет fn в транзакции: коммит при nil, откат при ошибке
+func (r *PostgresSubscriptionRepo) withTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, r.pool, fn)
+}
+
+// read выполняет читающий запрос на реплике, если они подключены
+func (r *PostgresSubscriptionRepo) read(ctx context.Context, fn func(q reader) error) error {
+	if r.replicas == nil {
+		return unwrapFinal(fn(r.pool))
+	}
+	return r.replicas.read(ctx, fn)
 }
 
 // List возвращает список подписок с фильтрацией
 func (r *PostgresSubscriptionRepo) List(ctx context.Context, filter models.SubscriptionFilter) ([]*models.Subscription, error) {
+	ctx, span := startSpan(ctx, "PostgresSubscriptionRepo.List")
+	defer span.End()
+
+	subscriptions := make([]*models.Subscription, 0)
+	err := r.Stream(ctx, filter, func(sub *models.Subscription) error {
+		subscriptions = append(subscriptions, sub)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// Stream построчно передает подписки в fn, не загружая всю выборку в память.
+// Итерация прекращается при первой ошибке fn.
+func (r *PostgresSubscriptionRepo) Stream(ctx context.Context, filter models.SubscriptionFilter, fn func(*models.Subscription) error) error {
+	ctx, span := startSpan(ctx, "PostgresSubscriptionRepo.Stream")
+	defer span.End()
+
+	query, args := buildListQuery(reqctx.Tenant(ctx), filter)
+
+	return r.read(ctx, func(q reader) error {
+		rows, err := q.Query(ctx, annotate(ctx, query), args...)
+		if err != nil {
+			return fmt.Errorf("failed to list subscriptions: %w", err)
+		}
+		defer rows.Close()
+
+		// после первой переданной подписки запрос нельзя повторить на основной БД
+		sent := false
+		final := func(err error) error {
+			if sent {
+				return &finalError{err: err}
+			}
+			return err
+		}
+
+		for rows.Next() {
+			sub, err := scanSubscription(rows)
+			if err != nil {
+				return final(fmt.Errorf("failed to scan subscription: %w", err))
+			}
+			sent = true
+			if err := fn(sub); err != nil {
+				return final(err)
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			return final(fmt.Errorf("rows iteration error: %w", err))
+		}
+
+		return nil
+	})
+}
+
+func buildListQuery(tenant string, filter models.SubscriptionFilter) (string, []interface{}) {
 	var query strings.Builder
 	query.WriteString(`
-		SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at
+		SELECT ` + subscriptionColumns + `
 		FROM subscriptions
 		WHERE 1=1
 	`)
@@ -136,6 +338,16 @@ func (r *PostgresSubscriptionRepo) List(ctx context.Context, filter models.Subsc
 	args := make([]interface{}, 0)
 	argPos := 1
 
+	if !filter.AllTenants {
+		query.WriteString(fmt.Sprintf(" AND tenant_id = $%d", argPos))
+		args = append(args, tenant)
+		argPos++
+	}
+
+	if !filter.IncludeDeleted {
+		query.WriteString(" AND deleted_at IS NULL")
+	}
+
 	if filter.UserID != nil {
 		query.WriteString(fmt.Sprintf(" AND user_id = $%d", argPos))
 		args = append(args, *filter.UserID)
@@ -148,6 +360,12 @@ func (r *PostgresSubscriptionRepo) List(ctx context.Context, filter models.Subsc
 		argPos++
 	}
 
+	if filter.ActiveOn != nil {
+		query.WriteString(fmt.Sprintf(" AND start_date <= $%d AND (end_date IS NULL OR end_date >= $%d)", argPos, argPos))
+		args = append(args, *filter.ActiveOn)
+		argPos++
+	}
+
 	query.WriteString(" ORDER BY created_at DESC")
 
 	if filter.Limit > 0 {
@@ -161,50 +379,49 @@ func (r *PostgresSubscriptionRepo) List(ctx context.Context, filter models.Subsc
 		args = append(args, filter.Offset)
 	}
 
-	rows, err := r.pool.Query(ctx, query.String(), args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
-	}
-	defer rows.Close()
-
-	subscriptions := make([]*models.Subscription, 0)
-	for rows.Next() {
-		var sub models.Subscription
-		err := rows.Scan(
-			&sub.ID,
-			&sub.ServiceName,
-			&sub.Price,
-			&sub.UserID,
-			&sub.StartDate,
-			&sub.EndDate,
-			&sub.CreatedAt,
-			&sub.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan subscription: %w", err)
-		}
-		subscriptions = append(subscriptions, &sub)
-	}
+	return query.String(), args
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration error: %w", err)
+func scanSubscription(row pgx.Row) (*models.Subscription, error) {
+	var sub models.Subscription
+	err := row.Scan(
+		&sub.ID,
+		&sub.TenantID,
+		&sub.ServiceName,
+		&sub.Price,
+		&sub.UserID,
+		&sub.StartDate,
+		&sub.EndDate,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+		&sub.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
 	}
-
-	return subscriptions, nil
+	return &sub, nil
 }
 
 // CalculateCost подсчитывает суммарную стоимость подписок за период
 func (r *PostgresSubscriptionRepo) CalculateCost(ctx context.Context, filter models.CostFilter) (int, error) {
+	ctx, span := startSpan(ctx, "PostgresSubscriptionRepo.CalculateCost")
+	defer span.End()
+
 	var query strings.Builder
 	query.WriteString(`
 		SELECT COALESCE(SUM(price), 0) as total_cost
 		FROM subscriptions
-		WHERE start_date <= $1
-		  AND (end_date IS NULL OR end_date >= $2)
+		WHERE tenant_id = $1
+		  AND start_date <= $2
+		  AND (end_date IS NULL OR end_date >= $3)
 	`)
 
-	args := []interface{}{filter.EndPeriod, filter.StartPeriod}
-	argPos := 3
+	args := []interface{}{reqctx.Tenant(ctx), filter.EndPeriod, filter.StartPeriod}
+	argPos := 4
+
+	if !filter.IncludeDeleted {
+		query.WriteString(" AND deleted_at IS NULL")
+	}
 
 	if filter.UserID != nil {
 		query.WriteString(fmt.Sprintf(" AND user_id = $%d", argPos))
@@ -218,10 +435,56 @@ func (r *PostgresSubscriptionRepo) CalculateCost(ctx context.Context, filter mod
 	}
 
 	var totalCost int
-	err := r.pool.QueryRow(ctx, query.String(), args...).Scan(&totalCost)
+	err := r.read(ctx, func(q reader) error {
+		return q.QueryRow(ctx, annotate(ctx, query.String()), args...).Scan(&totalCost)
+	})
 	if err != nil {
 		return 0, fmt.Errorf("failed to calculate cost: %w", err)
 	}
 
 	return totalCost, nil
 }
+
+// Stats возвращает число и суммарную стоимость подписок, действующих в месяце
+// month, по всем организациям
+func (r *PostgresSubscriptionRepo) Stats(ctx context.Context, month time.Time) ([]*models.SubscriptionStats, error) {
+	ctx, span := startSpan(ctx, "PostgresSubscriptionRepo.Stats")
+	defer span.End()
+
+	query := `
+		SELECT tenant_id, COUNT(*), COALESCE(SUM(price), 0)
+		FROM subscriptions
+		WHERE deleted_at IS NULL
+		  AND start_date <= $1
+		  AND (end_date IS NULL OR end_date >= $1)
+		GROUP BY tenant_id
+	`
+
+	var stats []*models.SubscriptionStats
+	err := r.read(ctx, func(q reader) error {
+		rows, err := q.Query(ctx, annotate(ctx, query), month)
+		if err != nil {
+			return fmt.Errorf("failed to get subscription stats: %w", err)
+		}
+		defer rows.Close()
+
+		stats = make([]*models.SubscriptionStats, 0)
+		for rows.Next() {
+			var s models.SubscriptionStats
+			if err := rows.Scan(&s.TenantID, &s.Active, &s.MonthlySpend); err != nil {
+				return fmt.Errorf("failed to scan subscription stats: %w", err)
+			}
+			stats = append(stats, &s)
+		}
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows iteration error: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,370 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrWebhookExists    = errors.New("webhook with this url already exists")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+const webhookColumns = `id, tenant_id, url, secret, event_types, active, created_at, updated_at`
+
+const deliveryColumns = `id, webhook_id, tenant_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+	last_error, response_status, created_at, updated_at, delivered_at`
+
+type PostgresWebhookRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresWebhookRepo(pool *pgxpool.Pool) *PostgresWebhookRepo {
+	return &PostgresWebhookRepo{pool: pool}
+}
+
+// Create регистрирует получателя событий организации запроса
+func (r *PostgresWebhookRepo) Create(ctx context.Context, wh *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (id, tenant_id, url, secret, event_types, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	wh.TenantID = reqctx.Tenant(ctx)
+
+	_, err := r.pool.Exec(ctx, annotate(ctx, query),
+		wh.ID,
+		wh.TenantID,
+		wh.URL,
+		wh.Secret,
+		wh.EventTypes,
+		wh.Active,
+		wh.CreatedAt,
+		wh.UpdatedAt,
+	)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrWebhookExists
+		}
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID возвращает получателя по ID
+func (r *PostgresWebhookRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1 AND tenant_id = $2`
+
+	wh, err := scanWebhook(r.pool.QueryRow(ctx, annotate(ctx, query), id, reqctx.Tenant(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return wh, nil
+}
+
+// List возвращает всех получателей
+func (r *PostgresWebhookRepo) List(ctx context.Context) ([]*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE tenant_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.pool.Query(ctx, annotate(ctx, query), reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]*models.Webhook, 0)
+	for rows.Next() {
+		wh, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, wh)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// Update обновляет адрес, секрет, типы событий и активность получателя
+func (r *PostgresWebhookRepo) Update(ctx context.Context, wh *models.Webhook) error {
+	query := `
+		UPDATE webhooks
+		SET url = $3, secret = $4, event_types = $5, active = $6, updated_at = $7
+		WHERE id = $1 AND tenant_id = $2
+	`
+
+	result, err := r.pool.Exec(ctx, annotate(ctx, query),
+		wh.ID,
+		reqctx.Tenant(ctx),
+		wh.URL,
+		wh.Secret,
+		wh.EventTypes,
+		wh.Active,
+		wh.UpdatedAt,
+	)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrWebhookExists
+		}
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// Delete удаляет получателя вместе с журналом доставок
+func (r *PostgresWebhookRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, annotate(ctx, `DELETE FROM webhooks WHERE id = $1 AND tenant_id = $2`), id, reqctx.Tenant(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// Enqueue создает доставки события всем активным получателям организации
+// события, подписанным на его тип. Повторная постановка того же события игнорируется
+func (r *PostgresWebhookRepo) Enqueue(ctx context.Context, event models.Event) (int64, error) {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, tenant_id, event_id, event_type, payload, next_attempt_at, created_at, updated_at)
+		SELECT id, tenant_id, $1, $2::text, $3, $4, $4, $4
+		FROM webhooks
+		WHERE active AND $2::text = ANY(event_types) AND tenant_id = $5
+		ON CONFLICT (webhook_id, event_id) DO NOTHING
+	`
+
+	result, err := r.pool.Exec(ctx, annotate(ctx, query), event.ID, event.Type, event, time.Now().UTC(), event.TenantID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// ClaimDue выбирает до limit доставок всех организаций, время которых наступило, и откладывает
+// их на lease, чтобы другие экземпляры сервиса не взяли их одновременно.
+// Если обработчик упадет, доставки вернутся в очередь по истечении lease
+func (r *PostgresWebhookRepo) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + deliveryColumns
+
+	now := time.Now().UTC()
+	rows, err := r.pool.Query(ctx, annotate(ctx, query), now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// RecordAttempt сохраняет итог попытки доставки
+func (r *PostgresWebhookRepo) RecordAttempt(ctx context.Context, id uuid.UUID, res models.DeliveryResult) error {
+	now := time.Now().UTC()
+
+	var (
+		status      = models.DeliveryPending
+		lastError   *string
+		deliveredAt *time.Time
+		nextAttempt = res.NextAttemptAt
+	)
+	switch {
+	case res.Err == nil:
+		status = models.DeliveryDelivered
+		deliveredAt = &now
+		nextAttempt = now
+	case res.Dead:
+		status = models.DeliveryDead
+		nextAttempt = now
+	}
+	if res.Err != nil {
+		msg := res.Err.Error()
+		lastError = &msg
+	}
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = attempts + 1, next_attempt_at = $3, last_error = $4,
+			response_status = $5, delivered_at = $6, updated_at = $7
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, annotate(ctx, query), id, status, nextAttempt, lastError, res.ResponseStatus, deliveredAt, now)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDeliveryNotFound
+	}
+
+	return nil
+}
+
+// Redeliver возвращает доставку из dead letter в очередь с обнулением попыток
+func (r *PostgresWebhookRepo) Redeliver(ctx context.Context, webhookID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = $3, updated_at = $3
+		WHERE id = $1 AND webhook_id = $2 AND tenant_id = $4 AND status = 'dead'
+		RETURNING ` + deliveryColumns
+
+	d, err := scanDelivery(r.pool.QueryRow(ctx, annotate(ctx, query), deliveryID, webhookID, time.Now().UTC(), reqctx.Tenant(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to redeliver webhook delivery: %w", err)
+	}
+
+	return d, nil
+}
+
+// ListDeliveries возвращает журнал доставок получателя, новые первыми
+func (r *PostgresWebhookRepo) ListDeliveries(ctx context.Context, filter models.DeliveryFilter) ([]*models.WebhookDelivery, error) {
+	var query strings.Builder
+	query.WriteString(`SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE webhook_id = $1 AND tenant_id = $2`)
+
+	args := []interface{}{filter.WebhookID, reqctx.Tenant(ctx)}
+	argPos := 3
+
+	if filter.Status != nil {
+		query.WriteString(fmt.Sprintf(" AND status = $%d", argPos))
+		args = append(args, *filter.Status)
+		argPos++
+	}
+
+	query.WriteString(" ORDER BY created_at DESC")
+
+	if filter.Limit > 0 {
+		query.WriteString(fmt.Sprintf(" LIMIT $%d", argPos))
+		args = append(args, filter.Limit)
+		argPos++
+	}
+
+	if filter.Offset > 0 {
+		query.WriteString(fmt.Sprintf(" OFFSET $%d", argPos))
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := r.pool.Query(ctx, annotate(ctx, query.String()), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func scanWebhook(row pgx.Row) (*models.Webhook, error) {
+	var wh models.Webhook
+	err := row.Scan(
+		&wh.ID,
+		&wh.TenantID,
+		&wh.URL,
+		&wh.Secret,
+		&wh.EventTypes,
+		&wh.Active,
+		&wh.CreatedAt,
+		&wh.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &wh, nil
+}
+
+func scanDelivery(row pgx.Row) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	err := row.Scan(
+		&d.ID,
+		&d.WebhookID,
+		&d.TenantID,
+		&d.EventID,
+		&d.EventType,
+		&d.Payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.LastError,
+		&d.ResponseStatus,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+		&d.DeliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// isUniqueViolation сообщает, нарушено ли ограничение уникальности
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation
+}
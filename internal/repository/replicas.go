@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+	"go.uber.org/zap"
+)
+
+// replicaLagQuery возвращает отставание реплики в секундах. Реплика, которая
+// воспроизвела все полученные записи WAL, не отстает, даже если на основной БД
+// давно не было изменений. NULL - отставание неизвестно (еще нет ни одной
+// воспроизведенной транзакции)
+const replicaLagQuery = `
+	SELECT CASE
+		WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())::float8
+	END
+`
+
+// reader - методы пула для читающих запросов
+type reader interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// ReplicaSet направляет читающие запросы на реплики по очереди. Реплика
+// используется, только если последняя проверка прошла успешно и ее отставание
+// не больше maxLag. Без подходящей реплики, при ошибке на реплике и для
+// запросов с reqctx.ReadYourWrites запрос выполняется на основной БД
+type ReplicaSet struct {
+	primary  *pgxpool.Pool
+	replicas []*replica
+	maxLag   time.Duration
+	interval time.Duration
+	next     atomic.Uint64
+	log      *zap.Logger
+}
+
+type replica struct {
+	name   string
+	pool   *pgxpool.Pool
+	usable atomic.Bool
+}
+
+func NewReplicaSet(primary *pgxpool.Pool, replicas []*pgxpool.Pool, maxLag, interval time.Duration, log *zap.Logger) *ReplicaSet {
+	s := &ReplicaSet{primary: primary, maxLag: maxLag, interval: interval, log: log}
+	for _, pool := range replicas {
+		cc := pool.Config().ConnConfig
+		s.replicas = append(s.replicas, &replica{
+			name: net.JoinHostPort(cc.Host, strconv.Itoa(int(cc.Port))),
+			pool: pool,
+		})
+	}
+	return s
+}
+
+// Run проверяет отставание реплик сразу и затем каждые interval до отмены ctx.
+// До первой проверки запросы выполняются на основной БД
+func (s *ReplicaSet) Run(ctx context.Context) {
+	s.log.Info("replica monitor started",
+		zap.Int("replicas", len(s.replicas)),
+		zap.Duration("max_lag", s.maxLag),
+		zap.Duration("interval", s.interval),
+	)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		for _, r := range s.replicas {
+			s.check(ctx, r)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.log.Info("replica monitor stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ReplicaSet) check(ctx context.Context, r *replica) {
+	ctx, cancel := context.WithTimeout(ctx, s.interval)
+	defer cancel()
+
+	var lag *float64
+	err := r.pool.QueryRow(ctx, replicaLagQuery).Scan(&lag)
+	switch {
+	case err != nil:
+		if ctx.Err() == nil || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s.setUsable(r, false, zap.Error(err))
+		}
+	case lag == nil:
+		s.setUsable(r, false, zap.String("reason", "replication lag is unknown"))
+	default:
+		d := time.Duration(*lag * float64(time.Second))
+		s.setUsable(r, d <= s.maxLag, zap.Duration("lag", d))
+	}
+}
+
+// setUsable меняет состояние реплики и пишет в лог только его изменения
+func (s *ReplicaSet) setUsable(r *replica, usable bool, fields ...zap.Field) {
+	if r.usable.Swap(usable) == usable {
+		return
+	}
+	fields = append(fields, zap.String("replica", r.name))
+	if usable {
+		s.log.Info("replica is in use", fields...)
+	} else {
+		s.log.Warn("replica is out of use, reading from primary", fields...)
+	}
+}
+
+// pick возвращает следующую подходящую реплику или nil
+func (s *ReplicaSet) pick(ctx context.Context) *replica {
+	if len(s.replicas) == 0 || reqctx.ReadYourWrites(ctx) {
+		return nil
+	}
+	start := s.next.Add(1)
+	for i := range uint64(len(s.replicas)) {
+		r := s.replicas[(start+i)%uint64(len(s.replicas))]
+		if r.usable.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// read выполняет читающий запрос fn на реплике или основной БД. Если запрос
+// на реплике завершился ошибкой, реплика исключается до следующей проверки,
+// а запрос повторяется на основной БД. Ошибка, обернутая в finalError,
+// возвращается без повтора
+func (s *ReplicaSet) read(ctx context.Context, fn func(q reader) error) error {
+	r := s.pick(ctx)
+	if r == nil {
+		return unwrapFinal(fn(s.primary))
+	}
+
+	err := fn(r.pool)
+	var final *finalError
+	if err == nil || errors.As(err, &final) || errors.Is(err, pgx.ErrNoRows) || ctx.Err() != nil {
+		return unwrapFinal(err)
+	}
+
+	s.setUsable(r, false, zap.Error(err))
+	return unwrapFinal(fn(s.primary))
+}
+
+// finalError - ошибка читающего запроса, после которой его нельзя повторить,
+// например потому что часть строк уже передана вызывающему
+type finalError struct {
+	err error
+}
+
+func (e *finalError) Error() string { return e.err.Error() }
+func (e *finalError) Unwrap() error { return e.err }
+
+func unwrapFinal(err error) error {
+	var final *finalError
+	if errors.As(err, &final) {
+		return final.err
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/untibullet/subscription-service-em/internal/models"
@@ -10,9 +11,75 @@ import (
 // SubscriptionRepository определяет методы работы с подписками
 type SubscriptionRepository interface {
 	Create(ctx context.Context, sub *models.Subscription) error
-	GetByID(ctx context.Context, id uuid.UUID) (*models.Subscription, error)
+	GetByID(ctx context.Context, id uuid.UUID, opts models.GetOptions) (*models.Subscription, error)
 	Update(ctx context.Context, sub *models.Subscription) error
-	Delete(ctx context.Context, id uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID) (*models.Subscription, error)
+	Restore(ctx context.Context, id uuid.UUID) (*models.Subscription, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
 	List(ctx context.Context, filter models.SubscriptionFilter) ([]*models.Subscription, error)
+	Stream(ctx context.Context, filter models.SubscriptionFilter, fn func(*models.Subscription) error) error
 	CalculateCost(ctx context.Context, filter models.CostFilter) (int, error)
 }
+
+// AuditRepository определяет методы чтения журнала изменений подписок
+type AuditRepository interface {
+	ListAudit(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEntry, error)
+}
+
+// WebhookRepository определяет методы работы с получателями событий и их доставками
+type WebhookRepository interface {
+	Create(ctx context.Context, wh *models.Webhook) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Webhook, error)
+	List(ctx context.Context) ([]*models.Webhook, error)
+	Update(ctx context.Context, wh *models.Webhook) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	Enqueue(ctx context.Context, event models.Event) (int64, error)
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, id uuid.UUID, res models.DeliveryResult) error
+	Redeliver(ctx context.Context, webhookID, deliveryID uuid.UUID) (*models.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, filter models.DeliveryFilter) ([]*models.WebhookDelivery, error)
+}
+
+// OutboxRepository определяет методы чтения и обслуживания outbox.
+// Запись событий об изменениях подписок выполняет SubscriptionRepository
+// в транзакции изменения
+type OutboxRepository interface {
+	Append(ctx context.Context, event models.Event) error
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxRecord, error)
+	MarkPublished(ctx context.Context, ids []int64) error
+	MarkFailed(ctx context.Context, id int64, cause error, next time.Time) error
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+// EventListener получает события изменения подписок, записанные любым
+// экземпляром сервиса
+type EventListener interface {
+	Listen(ctx context.Context, fn func(models.StreamEvent)) error
+}
+
+// APIKeyRepository определяет методы работы с API-ключами сервисных клиентов
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	List(ctx context.Context) ([]*models.APIKey, error)
+	Rotate(ctx context.Context, id uuid.UUID, prefix string, hash []byte) (*models.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+// RateLimitRepository хранит корзины токенов ограничения частоты запросов
+type RateLimitRepository interface {
+	Take(ctx context.Context, key string, capacity, rate float64) (float64, bool, error)
+	DeleteIdle(ctx context.Context, idle time.Duration) (int64, error)
+}
+
+// HealthRepository проверяет доступность БД и версию ее схемы
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int64, error)
+}
+
+// StatsRepository собирает сводные показатели подписок для метрик
+type StatsRepository interface {
+	Stats(ctx context.Context, month time.Time) ([]*models.SubscriptionStats, error)
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+)
+
+// EnableRowLevelSecurity перед выдачей соединения из пула записывает организацию
+// запроса в app.tenant_id, по которой политики RLS отбирают строки, а для
+// контекста reqctx.WithSystem без организации - app.system, открывающий строки
+// всех организаций. Без организации и без WithSystem политики не пропускают
+// ни одной строки
+func EnableRowLevelSecurity(cfg *pgxpool.Config) {
+	cfg.PrepareConn = func(ctx context.Context, conn *pgx.Conn) (bool, error) {
+		tenant, ok := reqctx.TenantFromContext(ctx)
+		system := !ok && reqctx.System(ctx)
+		query := `SELECT set_config('app.tenant_id', $1, false), set_config('app.system', $2, false)`
+		if _, err := conn.Exec(ctx, query, tenant, strconv.FormatBool(system)); err != nil {
+			return false, fmt.Errorf("failed to set tenant: %w", err)
+		}
+		return true, nil
+	}
+}
+
+// DisableRowLevelSecurity открывает соединениям пула строки всех организаций:
+// политики RLS не применяются, организацию отбирают сами запросы
+func DisableRowLevelSecurity(cfg *pgxpool.Config) {
+	cfg.ConnConfig.RuntimeParams["app.system"] = "true"
+}
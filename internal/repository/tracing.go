@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/untibullet/subscription-service-em/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan открывает span метода репозитория. Запросы pgx внутри метода
+// становятся его дочерними span, поэтому медленный запрос виден вместе с методом
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name)
+}
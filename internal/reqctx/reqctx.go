@@ -0,0 +1,90 @@
+package reqctx
+
+import "context"
+
+const (
+	// AnonymousActor - инициатор изменений, если он не определен
+	AnonymousActor = "anonymous"
+	// SystemActor - инициатор изменений, выполняемых фоновыми задачами
+	SystemActor = "system"
+	// DefaultTenant - организация, если она не определена
+	DefaultTenant = "default"
+)
+
+type ctxKey int
+
+const (
+	actorKey ctxKey = iota
+	requestIDKey
+	tenantKey
+	systemKey
+	readYourWritesKey
+)
+
+// WithActor сохраняет в контексте инициатора запроса
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor возвращает инициатора запроса или AnonymousActor
+func Actor(ctx context.Context) string {
+	if v, ok := ctx.Value(actorKey).(string); ok && v != "" {
+		return v
+	}
+	return AnonymousActor
+}
+
+// WithRequestID сохраняет в контексте идентификатор запроса
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID возвращает идентификатор запроса или пустую строку
+func RequestID(ctx context.Context) string {
+	v, _ := ctx.Value(requestIDKey).(string)
+	return v
+}
+
+// WithTenant сохраняет в контексте организацию, в рамках которой выполняется запрос
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// Tenant возвращает организацию запроса или DefaultTenant
+func Tenant(ctx context.Context) string {
+	if v, ok := TenantFromContext(ctx); ok {
+		return v
+	}
+	return DefaultTenant
+}
+
+// TenantFromContext возвращает организацию, если она явно задана в контексте.
+// Фоновые задачи работают без организации, с контекстом WithSystem
+func TenantFromContext(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(tenantKey).(string)
+	return v, ok && v != ""
+}
+
+// WithSystem помечает контекст фоновой задачи или служебной операции, которой
+// нужны данные всех организаций. Организация в контексте важнее этой отметки
+func WithSystem(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemKey, true)
+}
+
+// System сообщает, выполняется ли операция от имени системы
+func System(ctx context.Context) bool {
+	v, _ := ctx.Value(systemKey).(bool)
+	return v
+}
+
+// WithReadYourWrites требует читать данные запроса с основной БД, а не
+// с реплики, чтобы клиент увидел только что выполненные изменения
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey, true)
+}
+
+// ReadYourWrites сообщает, нужно ли читать данные запроса с основной БД
+func ReadYourWrites(ctx context.Context) bool {
+	v, _ := ctx.Value(readYourWritesKey).(bool)
+	return v
+}
@@ -0,0 +1,75 @@
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/untibullet/subscription-service-em/internal/health"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+	"go.uber.org/zap"
+)
+
+// Store - хранилище, из которого удаляются помеченные записи
+type Store interface {
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Purger периодически безвозвратно удаляет подписки, помеченные удаленными
+// дольше, чем period назад
+type Purger struct {
+	store    Store
+	period   time.Duration
+	interval time.Duration
+	beat     *health.Heartbeat
+	log      *zap.Logger
+}
+
+func NewPurger(store Store, period, interval time.Duration, log *zap.Logger) *Purger {
+	return &Purger{store: store, period: period, interval: interval, beat: health.NewHeartbeat(health.MissedRuns * interval), log: log}
+}
+
+// Check сообщает, выполняется ли очистка
+func (p *Purger) Check(ctx context.Context) error {
+	return p.beat.Check(ctx)
+}
+
+// Run выполняет очистку сразу и затем каждые interval до отмены ctx
+func (p *Purger) Run(ctx context.Context) {
+	p.log.Info("retention purger started",
+		zap.Duration("period", p.period),
+		zap.Duration("interval", p.interval),
+	)
+
+	// очистка попадает в журнал изменений от имени системы
+	ctx = reqctx.WithActor(ctx, reqctx.SystemActor)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			p.log.Info("retention purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	before := time.Now().UTC().Add(-p.period)
+	n, err := p.store.PurgeDeleted(ctx, before)
+	if err != nil {
+		if ctx.Err() == nil {
+			p.beat.Fail(err)
+			p.log.Error("purge failed", zap.Error(err))
+		}
+		return
+	}
+	p.beat.Beat()
+	if n > 0 {
+		p.log.Info("purged deleted subscriptions", zap.Int64("count", n), zap.Time("before", before))
+	}
+}
@@ -0,0 +1,124 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+	"go.uber.org/zap"
+)
+
+// fakeStore отдает ошибки из errs по очереди и запоминает вызовы
+type fakeStore struct {
+	errs  []error
+	calls chan call
+}
+
+type call struct {
+	before time.Time
+	actor  string
+}
+
+func newFakeStore(errs ...error) *fakeStore {
+	return &fakeStore{errs: errs, calls: make(chan call, 100)}
+}
+
+func (s *fakeStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	s.calls <- call{before: before, actor: reqctx.Actor(ctx)}
+	if len(s.errs) == 0 {
+		return 1, nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return 0, err
+}
+
+func TestPurgeCutoff(t *testing.T) {
+	const period = 30 * 24 * time.Hour
+	store := newFakeStore()
+	p := NewPurger(store, period, time.Hour, zap.NewNop())
+
+	start := time.Now().UTC()
+	p.purge(context.Background())
+	end := time.Now().UTC()
+
+	got := (<-store.calls).before
+	if got.Before(start.Add(-period)) || got.After(end.Add(-period)) {
+		t.Errorf("cutoff = %s, want now - %s (between %s and %s)", got, period, start.Add(-period), end.Add(-period))
+	}
+	if got.Location() != time.UTC {
+		t.Errorf("cutoff location = %s, want UTC", got.Location())
+	}
+}
+
+func TestPurgeHeartbeat(t *testing.T) {
+	errStore := errors.New("connection refused")
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		errs     []error
+		wait     time.Duration
+		wantErr  error
+	}{
+		{name: "successful purge", interval: time.Hour},
+		{name: "recovered after error", interval: time.Hour, errs: []error{errStore, nil}},
+		{name: "error within tolerance", interval: time.Hour, errs: []error{errStore}},
+		// допуск - три интервала без успешной очистки
+		{name: "error beyond tolerance", interval: 10 * time.Millisecond, errs: []error{errStore}, wait: 50 * time.Millisecond, wantErr: errStore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPurger(newFakeStore(tt.errs...), time.Hour, tt.interval, zap.NewNop())
+			for range max(len(tt.errs), 1) {
+				p.purge(context.Background())
+			}
+			time.Sleep(tt.wait)
+
+			err := p.Check(context.Background())
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Check() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPurgerRun(t *testing.T) {
+	store := newFakeStore()
+	p := NewPurger(store, time.Hour, 5*time.Millisecond, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	// первая очистка сразу при запуске, следующие - по интервалу
+	for i := range 3 {
+		select {
+		case c := <-store.calls:
+			if c.actor != reqctx.SystemActor {
+				t.Errorf("purge %d actor = %q, want %q", i, c.actor, reqctx.SystemActor)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("purge %d did not run", i)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop after context cancellation")
+	}
+}
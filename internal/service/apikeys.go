@@ -0,0 +1,233 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/auth"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"go.uber.org/zap"
+)
+
+// APIKeyHTTPService - HTTP-слой управления API-ключами сервисных клиентов
+type APIKeyHTTPService struct {
+	repo repository.APIKeyRepository
+	log  *zap.Logger
+}
+
+func NewAPIKeyHTTPService(repo repository.APIKeyRepository, log *zap.Logger) *APIKeyHTTPService {
+	return &APIKeyHTTPService{repo: repo, log: log}
+}
+
+func (s *APIKeyHTTPService) RegisterRoutes(e *echo.Echo) {
+	g := e.Group("/api/v1/api-keys", RequireAdmin)
+	g.POST("", s.Create)
+	g.GET("", s.List)
+	g.POST("/:id/rotate", s.Rotate)
+	g.DELETE("/:id", s.Revoke)
+}
+
+// DTOs
+
+// swagger:model CreateAPIKeyRequest
+type createAPIKeyReq struct {
+	Name      string         `json:"name"`
+	Scopes    []models.Scope `json:"scopes"`
+	UserID    *uuid.UUID     `json:"user_id,omitempty"`    // ограничить ключ подписками пользователя
+	ExpiresAt *time.Time     `json:"expires_at,omitempty"` // RFC 3339
+}
+
+// swagger:model apiKeyResp
+type apiKeyResp struct {
+	*models.APIKey
+	Key string `json:"key"` // показывается только при выпуске и ротации
+}
+
+// swagger:model apiKeyListResp
+type apiKeyListResp struct {
+	Data  []*models.APIKey `json:"data"`
+	Total int              `json:"total"`
+}
+
+// Helpers
+
+func validateScopes(scopes []models.Scope) error {
+	if len(scopes) == 0 {
+		return errors.New("scopes is required")
+	}
+	for _, sc := range scopes {
+		if !sc.Valid() {
+			return errors.New("unknown scope " + string(sc))
+		}
+	}
+	return nil
+}
+
+// Handlers
+
+// @Summary Выпустить API-ключ
+// @Description Создаёт ключ для сервисного клиента. Ключ возвращается только в этом ответе, сервис хранит лишь его хеш. Ключ передаётся в заголовке Authorization: ApiKey <ключ>
+// @ID create-api-key
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param input body createAPIKeyReq true "Данные ключа"
+// @Success 201 {object} apiKeyResp "Выпущенный ключ"
+// @Failure 400 {object} echo.Map "Неверный запрос"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 409 {object} echo.Map "Ключ с таким именем уже существует"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/api-keys [post]
+func (s *APIKeyHTTPService) Create(c echo.Context) error {
+	var req createAPIKeyReq
+	if err := c.Bind(&req); err != nil {
+		requestLog(c, s.log).Warn("bind error", zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid request")
+	}
+
+	if req.Name == "" || len(req.Name) > 255 {
+		return errorJSON(c, http.StatusBadRequest, "invalid name")
+	}
+	if err := validateScopes(req.Scopes); err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	now := time.Now().UTC()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return errorJSON(c, http.StatusBadRequest, "expires_at must be in the future")
+	}
+
+	plain, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		requestLog(c, s.log).Error("generate api key failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to create")
+	}
+
+	key := models.APIKey{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		Prefix:    prefix,
+		Hash:      hash,
+		Scopes:    req.Scopes,
+		UserID:    req.UserID,
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if key.ExpiresAt != nil {
+		expires := key.ExpiresAt.UTC()
+		key.ExpiresAt = &expires
+	}
+
+	if err := s.repo.Create(c.Request().Context(), &key); err != nil {
+		if errors.Is(err, repository.ErrAPIKeyExists) {
+			return errorJSON(c, http.StatusConflict, "api key already exists")
+		}
+		requestLog(c, s.log).Error("create api key failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to create")
+	}
+
+	return c.JSON(http.StatusCreated, apiKeyResp{APIKey: &key, Key: plain})
+}
+
+// @Summary Список API-ключей
+// @Description Возвращает все ключи, включая отозванные. Сами ключи не возвращаются
+// @ID list-api-keys
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Success 200 {object} apiKeyListResp "Список ключей"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/api-keys [get]
+func (s *APIKeyHTTPService) List(c echo.Context) error {
+	items, err := s.repo.List(c.Request().Context())
+	if err != nil {
+		requestLog(c, s.log).Error("list api keys failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to list")
+	}
+
+	return c.JSON(http.StatusOK, apiKeyListResp{Data: items, Total: len(items)})
+}
+
+// @Summary Ротировать API-ключ
+// @Description Выпускает новый секрет действующего ключа с теми же правами и сроком действия. Прежний секрет перестаёт действовать сразу
+// @ID rotate-api-key
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID ключа"
+// @Success 200 {object} apiKeyResp "Ключ с новым секретом"
+// @Failure 400 {object} echo.Map "Неверный формат ID"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 404 {object} echo.Map "Действующий ключ не найден"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/api-keys/{id}/rotate [post]
+func (s *APIKeyHTTPService) Rotate(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		requestLog(c, s.log).Warn("invalid id", zap.String("id", idStr), zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid id")
+	}
+
+	plain, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		requestLog(c, s.log).Error("generate api key failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to rotate")
+	}
+
+	key, err := s.repo.Rotate(c.Request().Context(), id, prefix, hash)
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return errorJSON(c, http.StatusNotFound, "not found")
+		}
+		requestLog(c, s.log).Error("rotate api key failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to rotate")
+	}
+
+	return c.JSON(http.StatusOK, apiKeyResp{APIKey: key, Key: plain})
+}
+
+// @Summary Отозвать API-ключ
+// @Description Отзывает ключ. Запись о ключе сохраняется в списке с отметкой revoked_at
+// @ID revoke-api-key
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID ключа"
+// @Success 204 "Ключ отозван"
+// @Failure 400 {object} echo.Map "Неверный формат ID"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 404 {object} echo.Map "Действующий ключ не найден"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/api-keys/{id} [delete]
+func (s *APIKeyHTTPService) Revoke(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		requestLog(c, s.log).Warn("invalid id", zap.String("id", idStr), zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid id")
+	}
+
+	if err := s.repo.Revoke(c.Request().Context(), id); err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return errorJSON(c, http.StatusNotFound, "not found")
+		}
+		requestLog(c, s.log).Error("revoke api key failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to revoke")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
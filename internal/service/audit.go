@@ -0,0 +1,147 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"go.uber.org/zap"
+)
+
+// swagger:model auditListResp
+type auditListResp struct {
+	Data  []*models.AuditEntry `json:"data"`
+	Total int                  `json:"total"`
+}
+
+// @Summary История изменений подписки
+// @Description Возвращает журнал изменений подписки (создание, обновления, удаление, восстановление), новые записи первыми
+// @ID subscription-history
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID идентификатор подписки"
+// @Param limit query int false "Количество элементов (макс. 500)" default(50)
+// @Param offset query int false "Смещение" default(0)
+// @Success 200 {object} auditListResp "Журнал изменений"
+// @Failure 400 {object} echo.Map "Неверный формат ID"
+// @Failure 404 {object} echo.Map "Подписка не найдена"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/subscriptions/{id}/history [get]
+func (s *HTTPService) History(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		requestLog(c, s.log).Warn("invalid id", zap.String("id", idStr), zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid id")
+	}
+
+	if !accessFrom(c).admin {
+		if _, err := s.getOwned(c, id, models.GetOptions{}); err != nil {
+			if err == repository.ErrNotFound {
+				return errorJSON(c, http.StatusNotFound, "not found")
+			}
+			requestLog(c, s.log).Error("get for history failed", zap.Error(err))
+			return errorJSON(c, http.StatusInternalServerError, "failed to get")
+		}
+	}
+
+	limit, offset := parsePage(c)
+	filter := models.AuditFilter{
+		SubscriptionID: &id,
+		Limit:          limit,
+		Offset:         offset,
+	}
+
+	return s.listAudit(c, filter)
+}
+
+// @Summary Журнал изменений
+// @Description Возвращает журнал изменений всех подписок с фильтрацией по инициатору, действию и времени, новые записи первыми
+// @ID list-audit
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Param actor query string false "Инициатор изменения"
+// @Param action query string false "Действие" Enums(create, update, delete, restore, purge)
+// @Param subscription_id query string false "UUID подписки"
+// @Param from query string false "Начало интервала, включительно (RFC 3339)"
+// @Param to query string false "Конец интервала, не включительно (RFC 3339)"
+// @Param limit query int false "Количество элементов (макс. 500)" default(50)
+// @Param offset query int false "Смещение" default(0)
+// @Success 200 {object} auditListResp "Журнал изменений"
+// @Failure 400 {object} echo.Map "Неверный запрос"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/audit [get]
+func (s *HTTPService) Audit(c echo.Context) error {
+	filter, err := s.parseAuditFilter(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	filter.Limit, filter.Offset = parsePage(c)
+
+	return s.listAudit(c, filter)
+}
+
+func (s *HTTPService) listAudit(c echo.Context, filter models.AuditFilter) error {
+	items, err := s.audit.ListAudit(c.Request().Context(), filter)
+	if err != nil {
+		requestLog(c, s.log).Error("list audit failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to list audit")
+	}
+
+	return c.JSON(http.StatusOK, auditListResp{Data: items, Total: len(items)})
+}
+
+// parseAuditFilter разбирает фильтры журнала изменений. Текст ошибки можно отдавать клиенту.
+func (s *HTTPService) parseAuditFilter(c echo.Context) (models.AuditFilter, error) {
+	var filter models.AuditFilter
+
+	if v := c.QueryParam("actor"); v != "" {
+		filter.Actor = &v
+	}
+	if v := c.QueryParam("action"); v != "" {
+		action := models.AuditAction(v)
+		switch action {
+		case models.AuditCreate, models.AuditUpdate, models.AuditDelete, models.AuditRestore, models.AuditPurge:
+			filter.Action = &action
+		default:
+			return filter, errors.New("invalid action")
+		}
+	}
+	if v := c.QueryParam("subscription_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			requestLog(c, s.log).Warn("invalid subscription_id", zap.String("value", v), zap.Error(err))
+			return filter, errors.New("invalid subscription_id")
+		}
+		filter.SubscriptionID = &id
+	}
+	if v := c.QueryParam("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			requestLog(c, s.log).Warn("invalid from", zap.String("value", v), zap.Error(err))
+			return filter, errors.New("invalid from")
+		}
+		filter.From = &t
+	}
+	if v := c.QueryParam("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			requestLog(c, s.log).Warn("invalid to", zap.String("value", v), zap.Error(err))
+			return filter, errors.New("invalid to")
+		}
+		filter.To = &t
+	}
+
+	return filter, nil
+}
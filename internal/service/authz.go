@@ -0,0 +1,117 @@
+package service
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/auth"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"go.uber.org/zap"
+)
+
+// accessKey - ключ прав клиента в echo.Context
+const accessKey = "access"
+
+// access - права клиента на подписки. Администратор видит все подписки,
+// обычный пользователь - только свои. API-ключ без привязки к пользователю
+// видит все подписки, но не получает роль администратора
+type access struct {
+	admin  bool
+	all    bool // доступны подписки всех пользователей
+	userID uuid.UUID
+	scopes []models.Scope // nil - без ограничений
+}
+
+// owns сообщает, доступны ли клиенту подписки пользователя userID
+func (a access) owns(userID uuid.UUID) bool {
+	return a.all || a.userID == userID
+}
+
+// allows сообщает, есть ли у клиента право scope
+func (a access) allows(scope models.Scope) bool {
+	return a.scopes == nil || slices.Contains(a.scopes, scope)
+}
+
+// restrict ограничивает фильтр по пользователю подписками клиента. Возвращает
+// false, если фильтр запрашивает чужие подписки
+func (a access) restrict(userID **uuid.UUID) bool {
+	if a.all {
+		return true
+	}
+	if *userID != nil {
+		return **userID == a.userID
+	}
+	id := a.userID
+	*userID = &id
+	return true
+}
+
+// noAccess - права клиента, для которого Authorization не определил права
+var noAccess = access{scopes: []models.Scope{}}
+
+// Authorization определяет права клиента по результату аутентификации.
+// Без аутентификации (секция auth выключена) клиенту доступны подписки всех
+// пользователей, но не действия администратора, если anonymousAdmin не включен
+func Authorization(anonymousAdmin bool, log *zap.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p := auth.FromContext(c.Request().Context())
+			switch {
+			case p == nil:
+				c.Set(accessKey, access{admin: anonymousAdmin, all: true})
+				return next(c)
+			case p.Admin:
+				c.Set(accessKey, access{admin: true, all: true})
+				return next(c)
+			case p.APIKey && p.UserID == "":
+				c.Set(accessKey, access{all: true, scopes: p.Scopes})
+				return next(c)
+			}
+
+			userID, err := uuid.Parse(p.UserID)
+			if err != nil {
+				log.Warn("token has no user id", zap.String("subject", p.Subject), zap.String("user_id", p.UserID))
+				return errorJSON(c, http.StatusForbidden, "token has no user id")
+			}
+			acc := access{userID: userID}
+			if p.APIKey {
+				acc.scopes = p.Scopes
+			}
+			c.Set(accessKey, acc)
+			return next(c)
+		}
+	}
+}
+
+// accessFrom возвращает права клиента. Если Authorization не подключен,
+// клиенту ничего не доступно
+func accessFrom(c echo.Context) access {
+	if a, ok := c.Get(accessKey).(access); ok {
+		return a
+	}
+	return noAccess
+}
+
+// RequireAdmin пропускает только администраторов
+func RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !accessFrom(c).admin {
+			return errorJSON(c, http.StatusForbidden, "admin role required")
+		}
+		return next(c)
+	}
+}
+
+// requireScope пропускает клиентов с правом scope
+func requireScope(scope models.Scope) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !accessFrom(c).allows(scope) {
+				return errorJSON(c, http.StatusForbidden, "missing scope "+string(scope))
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/auth"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"go.uber.org/zap"
+)
+
+func TestAuthorization(t *testing.T) {
+	const userID = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+
+	tests := []struct {
+		name           string
+		principal      *auth.Principal
+		anonymousAdmin bool
+		noAuthz        bool // Authorization не подключен
+		wantAdmin      int  // статус ручки администратора
+		wantList       int  // статус ручки со scope subscriptions:read
+	}{
+		{name: "anonymous", wantAdmin: http.StatusForbidden, wantList: http.StatusOK},
+		{name: "anonymous admin for development", anonymousAdmin: true, wantAdmin: http.StatusOK, wantList: http.StatusOK},
+		{name: "without authorization middleware", noAuthz: true, wantAdmin: http.StatusForbidden, wantList: http.StatusForbidden},
+		{name: "admin", principal: &auth.Principal{UserID: userID, Admin: true}, wantAdmin: http.StatusOK, wantList: http.StatusOK},
+		{name: "user", principal: &auth.Principal{UserID: userID}, wantAdmin: http.StatusForbidden, wantList: http.StatusOK},
+		{name: "user without id", principal: &auth.Principal{Subject: "svc"}, wantAdmin: http.StatusForbidden, wantList: http.StatusForbidden},
+		{
+			name:      "api key without scope",
+			principal: &auth.Principal{APIKey: true, Scopes: []models.Scope{models.ScopeSubscriptionsWrite}},
+			wantAdmin: http.StatusForbidden,
+			wantList:  http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			if tt.principal != nil {
+				e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+					return func(c echo.Context) error {
+						c.SetRequest(c.Request().WithContext(auth.WithPrincipal(c.Request().Context(), tt.principal)))
+						return next(c)
+					}
+				})
+			}
+			if !tt.noAuthz {
+				e.Use(Authorization(tt.anonymousAdmin, zap.NewNop()))
+			}
+			ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+			e.GET("/admin", ok, RequireAdmin)
+			e.GET("/list", ok, requireScope(models.ScopeSubscriptionsRead))
+
+			for path, want := range map[string]int{"/admin": tt.wantAdmin, "/list": tt.wantList} {
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+				if rec.Code != want {
+					t.Errorf("GET %s = %d, want %d", path, rec.Code, want)
+				}
+			}
+		})
+	}
+}
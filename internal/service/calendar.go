@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/ical"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"go.uber.org/zap"
+)
+
+const calendarProdID = "-//subscription-service//renewals//RU"
+
+// @Summary Календарь продлений пользователя
+// @Description Возвращает iCalendar (RFC 5545) с ежемесячно повторяющимся событием для каждой активной подписки пользователя
+// @ID user-renewals-calendar
+// @Tags users
+// @Produce text/calendar
+// @Param user_id path string true "UUID пользователя"
+// @Param X-Read-Your-Writes header bool false "Читать с основной БД, а не с реплики" default(false)
+// @Success 200 {string} string "Календарь в формате iCalendar"
+// @Failure 400 {object} echo.Map "Неверный формат user_id"
+// @Failure 404 {object} echo.Map "Календарь другого пользователя"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/users/{user_id}/renewals.ics [get]
+func (s *HTTPService) RenewalsCalendar(c echo.Context) error {
+	userIDStr := c.Param("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		requestLog(c, s.log).Warn("invalid user_id", zap.String("value", userIDStr), zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid user_id")
+	}
+	if !accessFrom(c).owns(userID) {
+		return errorJSON(c, http.StatusNotFound, "not found")
+	}
+
+	items, err := s.repo.List(c.Request().Context(), models.SubscriptionFilter{UserID: &userID})
+	if err != nil {
+		requestLog(c, s.log).Error("list for calendar failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to list")
+	}
+
+	now := time.Now().UTC()
+	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	cal := ical.Calendar{
+		ProdID: calendarProdID,
+		Name:   "Продления подписок",
+		Events: make([]ical.Event, 0, len(items)),
+	}
+	for _, sub := range items {
+		// завершившиеся подписки больше не продлеваются
+		if sub.EndDate != nil && sub.EndDate.Before(currentMonth) {
+			continue
+		}
+		cal.Events = append(cal.Events, renewalEvent(sub, now))
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, ical.ContentType)
+	res.Header().Set(echo.HeaderContentDisposition, `inline; filename="renewals.ics"`)
+	res.WriteHeader(http.StatusOK)
+	if err := cal.Encode(res); err != nil {
+		requestLog(c, s.log).Error("calendar encode failed", zap.Error(err))
+	}
+	return nil
+}
+
+// renewalEvent строит повторяющееся событие продления подписки
+func renewalEvent(sub *models.Subscription, stamp time.Time) ical.Event {
+	return ical.Event{
+		// UID стабилен между запросами, поэтому клиенты обновляют событие, а не дублируют
+		UID:          sub.ID.String() + "@subscription-service",
+		Stamp:        stamp,
+		LastModified: sub.UpdatedAt,
+		Date:         sub.StartDate,
+		Summary:      fmt.Sprintf("Продление подписки %s", sub.ServiceName),
+		Description:  fmt.Sprintf("Сервис: %s\nСтоимость: %d ₽ в месяц", sub.ServiceName, sub.Price),
+		Recurrence: &ical.Recurrence{
+			Freq:  ical.FreqMonthly,
+			Until: sub.EndDate,
+		},
+	}
+}
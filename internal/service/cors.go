@@ -0,0 +1,42 @@
+package service
+
+import (
+	"slices"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// AllowedOrigins - источники, которым разрешены запросы из браузера.
+// Список можно заменить без перезапуска, "*" разрешает любой источник
+type AllowedOrigins struct {
+	origins atomic.Pointer[[]string]
+}
+
+func NewAllowedOrigins(origins []string) *AllowedOrigins {
+	a := &AllowedOrigins{}
+	a.Set(origins)
+	return a
+}
+
+// Set заменяет список источников
+func (a *AllowedOrigins) Set(origins []string) {
+	origins = slices.Clone(origins)
+	a.origins.Store(&origins)
+}
+
+// Allow сообщает, разрешены ли запросы с источника origin
+func (a *AllowedOrigins) Allow(origin string) (bool, error) {
+	origins := *a.origins.Load()
+	return slices.Contains(origins, "*") || slices.Contains(origins, origin), nil
+}
+
+// CORS разрешает запросы из браузера с источников origins. Браузерный клиент
+// видит X-Request-ID, чтобы сообщить его в поддержку
+func CORS(origins *AllowedOrigins) echo.MiddlewareFunc {
+	return middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOriginFunc: origins.Allow,
+		ExposeHeaders:   []string{echo.HeaderXRequestID},
+	})
+}
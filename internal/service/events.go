@@ -0,0 +1,135 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+	"github.com/untibullet/subscription-service-em/internal/stream"
+	"go.uber.org/zap"
+)
+
+// HeaderLastEventID - заголовок, с которым EventSource переподключается к потоку
+const HeaderLastEventID = "Last-Event-ID"
+
+// EventStreamHTTPService - HTTP-слой потока изменений подписок (Server-Sent Events)
+type EventStreamHTTPService struct {
+	broker    *stream.Broker
+	heartbeat time.Duration
+	log       *zap.Logger
+}
+
+func NewEventStreamHTTPService(broker *stream.Broker, heartbeat time.Duration, log *zap.Logger) *EventStreamHTTPService {
+	return &EventStreamHTTPService{broker: broker, heartbeat: heartbeat, log: log}
+}
+
+func (s *EventStreamHTTPService) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/v1/subscriptions/events", s.Events, requireScope(models.ScopeSubscriptionsRead))
+}
+
+// @Summary Поток изменений подписок
+// @Description Server-Sent Events: события subscription.created, subscription.updated и subscription.deleted организации клиента со всех экземпляров сервиса. Поле id события - его порядковый номер; при переподключении с заголовком Last-Event-ID пропущенные события отдаются из ограниченного буфера. Раз в heartbeat отправляется комментарий-пинг.
+// @ID subscription-events
+// @Tags subscriptions
+// @Produce text/event-stream
+// @Param user_id query string false "UUID пользователя"
+// @Param service_name query string false "Название сервиса"
+// @Param Last-Event-ID header int false "Номер последнего полученного события"
+// @Success 200 {object} models.Event "Поток событий"
+// @Failure 400 {object} echo.Map "Неверный запрос"
+// @Failure 404 {object} echo.Map "События другого пользователя"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Security BearerAuth
+// @Router /api/v1/subscriptions/events [get]
+func (s *EventStreamHTTPService) Events(c echo.Context) error {
+	filter := stream.Filter{TenantID: reqctx.Tenant(c.Request().Context())}
+	if v := c.QueryParam("user_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			requestLog(c, s.log).Warn("invalid user_id", zap.String("value", v), zap.Error(err))
+			return errorJSON(c, http.StatusBadRequest, "invalid user_id")
+		}
+		filter.UserID = &id
+	}
+	if v := c.QueryParam("service_name"); v != "" {
+		filter.ServiceName = &v
+	}
+	if !accessFrom(c).restrict(&filter.UserID) {
+		return errorJSON(c, http.StatusNotFound, "not found")
+	}
+
+	var lastSeq int64
+	if v := c.Request().Header.Get(HeaderLastEventID); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return errorJSON(c, http.StatusBadRequest, "invalid Last-Event-ID")
+		}
+		lastSeq = n
+	}
+
+	replay, sub := s.broker.Subscribe(filter, lastSeq)
+	defer sub.Close()
+
+	disableWriteTimeout(c)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	// отключаем буферизацию в nginx
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	for _, event := range replay {
+		if err := writeEvent(res, event); err != nil {
+			return nil
+		}
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(s.heartbeat)
+	defer heartbeat.Stop()
+
+	ctx := c.Request().Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-sub.C:
+			if !ok {
+				// брокер отключил клиента, EventSource переподключится с Last-Event-ID
+				return nil
+			}
+			if err := writeEvent(res, event); err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": ping\n\n"); err != nil {
+				return nil
+			}
+		}
+		res.Flush()
+	}
+}
+
+// disableWriteTimeout снимает server.write_timeout с долгого ответа: потока
+// событий или выгрузки, которые иначе обрывались бы по таймауту
+func disableWriteTimeout(c echo.Context) {
+	_ = http.NewResponseController(c.Response()).SetWriteDeadline(time.Time{})
+}
+
+// writeEvent пишет событие в формате text/event-stream
+func writeEvent(res *echo.Response, event models.StreamEvent) error {
+	data, err := json.Marshal(event.Event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Event.Type, data)
+	return err
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/export"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"go.uber.org/zap"
+)
+
+// exportFlushEvery - через сколько строк сбрасывать буфер клиенту
+const exportFlushEvery = 500
+
+// @Summary Выгрузить подписки
+// @Description Потоково выгружает все подписки, подходящие под фильтры списка, в CSV, NDJSON или XLSX. Ограничение в 500 записей на выгрузку не действует.
+// @ID export-subscriptions
+// @Tags subscriptions
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "Формат выгрузки" Enums(csv, ndjson, xlsx) default(csv)
+// @Param user_id query string false "UUID пользователя"
+// @Param service_name query string false "Название сервиса"
+// @Param include_deleted query bool false "Включать удалённые подписки (для администраторов)" default(false)
+// @Param limit query int false "Количество элементов (по умолчанию без ограничения)"
+// @Param offset query int false "Смещение" default(0)
+// @Param X-Read-Your-Writes header bool false "Читать с основной БД, а не с реплики" default(false)
+// @Success 200 {file} file "Файл выгрузки"
+// @Failure 400 {object} echo.Map "Неверный запрос"
+// @Failure 404 {object} echo.Map "Подписки другого пользователя"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/subscriptions/export [get]
+func (s *HTTPService) Export(c echo.Context) error {
+	format := export.FormatCSV
+	if v := c.QueryParam("format"); v != "" {
+		f, err := export.ParseFormat(v)
+		if err != nil {
+			requestLog(c, s.log).Warn("invalid format", zap.String("value", v), zap.Error(err))
+			return errorJSON(c, http.StatusBadRequest, "invalid format")
+		}
+		format = f
+	}
+
+	filter, err := s.parseListFilter(c)
+	if err != nil {
+		return filterError(c, err)
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return errorJSON(c, http.StatusBadRequest, "invalid limit")
+		}
+		filter.Limit = n
+	}
+	if v := c.QueryParam("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return errorJSON(c, http.StatusBadRequest, "invalid offset")
+		}
+		filter.Offset = n
+	}
+
+	disableWriteTimeout(c)
+
+	res := c.Response()
+	filename := fmt.Sprintf("subscriptions-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	res.Header().Set(echo.HeaderContentType, format.ContentType())
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+
+	w, err := export.NewWriter(format, res)
+	if err != nil {
+		requestLog(c, s.log).Error("export writer failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to export")
+	}
+
+	// после первой записи статус уже отправлен, поэтому ошибки дальше только логируем
+	rows := 0
+	err = s.repo.Stream(c.Request().Context(), filter, func(sub *models.Subscription) error {
+		if err := w.Write(sub); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushEvery == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			res.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		requestLog(c, s.log).Error("export failed", zap.Int("rows", rows), zap.String("format", string(format)), zap.Error(err))
+		if !res.Committed {
+			res.Header().Del(echo.HeaderContentDisposition)
+			return errorJSON(c, http.StatusInternalServerError, "failed to export")
+		}
+		// прерываем соединение, чтобы клиент не принял обрезанный файл за полный
+		panic(http.ErrAbortHandler)
+	}
+
+	res.Flush()
+	return nil
+}
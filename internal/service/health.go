@@ -0,0 +1,56 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/health"
+	"go.uber.org/zap"
+)
+
+// HealthHTTPService - проверки живости и готовности для оркестратора
+type HealthHTTPService struct {
+	checker *health.Checker
+	log     *zap.Logger
+}
+
+func NewHealthHTTPService(checker *health.Checker, log *zap.Logger) *HealthHTTPService {
+	return &HealthHTTPService{checker: checker, log: log}
+}
+
+func (s *HealthHTTPService) RegisterRoutes(e *echo.Echo) {
+	e.GET("/healthz", s.Liveness)
+	e.GET("/readyz", s.Readiness)
+}
+
+// @Summary Проверка живости
+// @Description Отвечает, пока процесс работает. Зависимости не проверяются
+// @ID liveness
+// @Tags health
+// @Produce json
+// @Success 200 {object} echo.Map "Процесс работает"
+// @Router /healthz [get]
+func (s *HealthHTTPService) Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{"status": health.StatusOK})
+}
+
+// @Summary Проверка готовности
+// @Description Проверяет соединение с БД, версию схемы БД и фоновые задачи. Во время остановки сервиса возвращает 503 со статусом draining
+// @ID readiness
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report "Сервис готов"
+// @Failure 503 {object} health.Report "Сервис не готов"
+// @Router /readyz [get]
+func (s *HealthHTTPService) Readiness(c echo.Context) error {
+	report := s.checker.Check(c.Request().Context())
+	if !report.Ready() {
+		for name, comp := range report.Components {
+			if comp.Status != health.StatusOK {
+				requestLog(c, s.log).Warn("readiness check failed", zap.String("component", name), zap.String("error", comp.Error))
+			}
+		}
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,22 +14,34 @@ import (
 )
 
 type HTTPService struct {
-	repo repository.SubscriptionRepository
-	log  *zap.Logger
+	repo  repository.SubscriptionRepository
+	audit repository.AuditRepository
+	log   *zap.Logger
 }
 
-func NewHTTPService(repo repository.SubscriptionRepository, log *zap.Logger) *HTTPService {
-	return &HTTPService{repo: repo, log: log}
+func NewHTTPService(repo repository.SubscriptionRepository, audit repository.AuditRepository, log *zap.Logger) *HTTPService {
+	return &HTTPService{repo: repo, audit: audit, log: log}
 }
 
 func (s *HTTPService) RegisterRoutes(e *echo.Echo) {
+	read := requireScope(models.ScopeSubscriptionsRead)
+	write := requireScope(models.ScopeSubscriptionsWrite)
+
 	g := e.Group("/api/v1/subscriptions")
-	g.POST("", s.Create)
-	g.GET("/:id", s.GetByID)
-	g.PUT("/:id", s.Update)
-	g.DELETE("/:id", s.Delete)
-	g.GET("", s.List)
-	g.GET("/cost", s.CalculateCost)
+	g.POST("", s.Create, write)
+	g.GET("/:id", s.GetByID, read)
+	g.PUT("/:id", s.Update, write)
+	g.DELETE("/:id", s.Delete, write)
+	g.POST("/:id/restore", s.Restore, RequireAdmin)
+	g.GET("/:id/history", s.History, read)
+	g.GET("", s.List, read)
+	g.GET("/cost", s.CalculateCost, requireScope(models.ScopeReportsRead))
+	g.GET("/export", s.Export, read)
+
+	u := e.Group("/api/v1/users")
+	u.GET("/:user_id/renewals.ics", s.RenewalsCalendar, read)
+
+	e.GET("/api/v1/audit", s.Audit, RequireAdmin)
 }
 
 // DTOs
@@ -53,7 +66,7 @@ type updateReq struct {
 // swagger:model listResp
 type listResp struct {
 	Data  []*models.Subscription `json:"data"`
-	Total int                   `json:"total"`
+	Total int                    `json:"total"`
 }
 
 // swagger:model costResp
@@ -73,6 +86,90 @@ func parseMonth(s string) (time.Time, error) {
 	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
 }
 
+// parsePage разбирает limit и offset (по умолчанию 50, максимум 500)
+func parsePage(c echo.Context) (limit, offset int) {
+	limit = 50
+	if v := c.QueryParam("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 500 {
+			limit = n
+		}
+	}
+	if v := c.QueryParam("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			offset = n
+		}
+	}
+	return limit, offset
+}
+
+// parseListFilter разбирает фильтры выборки подписок из query-параметров.
+// Текст ошибки можно отдавать клиенту.
+func (s *HTTPService) parseListFilter(c echo.Context) (models.SubscriptionFilter, error) {
+	var filter models.SubscriptionFilter
+
+	if v := c.QueryParam("user_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			requestLog(c, s.log).Warn("invalid user_id", zap.String("value", v), zap.Error(err))
+			return filter, errors.New("invalid user_id")
+		}
+		filter.UserID = &id
+	}
+	if v := c.QueryParam("service_name"); v != "" {
+		filter.ServiceName = &v
+	}
+	if !accessFrom(c).restrict(&filter.UserID) {
+		return filter, errForeignUser
+	}
+
+	includeDeleted, err := parseIncludeDeleted(c)
+	if err != nil {
+		return filter, err
+	}
+	filter.IncludeDeleted = includeDeleted
+
+	return filter, nil
+}
+
+// parseIncludeDeleted разбирает флаг include_deleted (выдача удаленных подписок).
+// Для обычных пользователей флаг игнорируется
+func parseIncludeDeleted(c echo.Context) (bool, error) {
+	v := c.QueryParam("include_deleted")
+	if v == "" {
+		return false, nil
+	}
+	include, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.New("invalid include_deleted")
+	}
+	return include && accessFrom(c).admin, nil
+}
+
+// errForeignUser - фильтр запрашивает подписки другого пользователя
+var errForeignUser = errors.New("not found")
+
+// filterError отвечает на ошибку разбора фильтра. Чужие подписки для клиента
+// не существуют, поэтому на них отвечаем 404, а не 403
+func filterError(c echo.Context, err error) error {
+	if errors.Is(err, errForeignUser) {
+		return errorJSON(c, http.StatusNotFound, err.Error())
+	}
+	return errorJSON(c, http.StatusBadRequest, err.Error())
+}
+
+// getOwned возвращает подписку, если она доступна клиенту. Чужая подписка
+// неотличима от несуществующей: repository.ErrNotFound
+func (s *HTTPService) getOwned(c echo.Context, id uuid.UUID, opts models.GetOptions) (*models.Subscription, error) {
+	sub, err := s.repo.GetByID(c.Request().Context(), id, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !accessFrom(c).owns(sub.UserID) {
+		return nil, repository.ErrNotFound
+	}
+	return sub, nil
+}
+
 // Handlers
 
 // @Summary Создать новую подписку
@@ -84,27 +181,34 @@ func parseMonth(s string) (time.Time, error) {
 // @Param input body createReq true "Данные подписки"
 // @Success 201 {object} models.Subscription "Созданная подписка"
 // @Failure 400 {object} echo.Map "Неверный запрос"
+// @Failure 403 {object} echo.Map "Подписка для другого пользователя"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
 // @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
 // @Router /api/v1/subscriptions [post]
 func (s *HTTPService) Create(c echo.Context) error {
 	var req createReq
 	if err := c.Bind(&req); err != nil {
-		s.log.Warn("bind error", zap.Error(err))
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+		requestLog(c, s.log).Warn("bind error", zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid request")
+	}
+
+	if !accessFrom(c).owns(req.UserID) {
+		return errorJSON(c, http.StatusForbidden, "cannot create subscription for another user")
 	}
 
 	start, err := parseMonth(req.StartDate)
 	if err != nil {
-		s.log.Warn("invalid start_date", zap.String("value", req.StartDate), zap.Error(err))
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid start_date"})
+		requestLog(c, s.log).Warn("invalid start_date", zap.String("value", req.StartDate), zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid start_date")
 	}
 
 	var endPtr *time.Time
 	if req.EndDate != nil {
 		end, err := parseMonth(*req.EndDate)
 		if err != nil {
-			s.log.Warn("invalid end_date", zap.String("value", *req.EndDate), zap.Error(err))
-			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid end_date"})
+			requestLog(c, s.log).Warn("invalid end_date", zap.String("value", *req.EndDate), zap.Error(err))
+			return errorJSON(c, http.StatusBadRequest, "invalid end_date")
 		}
 		endPtr = &end
 	}
@@ -122,8 +226,8 @@ func (s *HTTPService) Create(c echo.Context) error {
 	}
 
 	if err := s.repo.Create(c.Request().Context(), &sub); err != nil {
-		s.log.Error("create failed", zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to create"})
+		requestLog(c, s.log).Error("create failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to create")
 	}
 
 	return c.JSON(http.StatusCreated, sub)
@@ -136,26 +240,34 @@ func (s *HTTPService) Create(c echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param id path string true "UUID идентификатор подписки"
+// @Param include_deleted query bool false "Возвращать удалённую подписку (для администраторов)" default(false)
 // @Success 200 {object} models.Subscription "Информация о подписке"
 // @Failure 400 {object} echo.Map "Неверный формат ID"
 // @Failure 404 {object} echo.Map "Подписка не найдена"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
 // @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
 // @Router /api/v1/subscriptions/{id} [get]
 func (s *HTTPService) GetByID(c echo.Context) error {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		s.log.Warn("invalid id", zap.String("id", idStr), zap.Error(err))
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid id"})
+		requestLog(c, s.log).Warn("invalid id", zap.String("id", idStr), zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid id")
+	}
+
+	includeDeleted, err := parseIncludeDeleted(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
 	}
 
-	sub, err := s.repo.GetByID(c.Request().Context(), id)
+	sub, err := s.getOwned(c, id, models.GetOptions{IncludeDeleted: includeDeleted})
 	if err != nil {
 		if err == repository.ErrNotFound {
-			return c.JSON(http.StatusNotFound, echo.Map{"error": "not found"})
+			return errorJSON(c, http.StatusNotFound, "not found")
 		}
-		s.log.Error("get failed", zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to get"})
+		requestLog(c, s.log).Error("get failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to get")
 	}
 
 	return c.JSON(http.StatusOK, sub)
@@ -172,30 +284,32 @@ func (s *HTTPService) GetByID(c echo.Context) error {
 // @Success 200 {object} models.Subscription "Обновлённая подписка"
 // @Failure 400 {object} echo.Map "Неверный запрос"
 // @Failure 404 {object} echo.Map "Подписка не найдена"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
 // @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
 // @Router /api/v1/subscriptions/{id} [put]
 func (s *HTTPService) Update(c echo.Context) error {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		s.log.Warn("invalid id", zap.String("id", idStr), zap.Error(err))
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid id"})
+		requestLog(c, s.log).Warn("invalid id", zap.String("id", idStr), zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid id")
 	}
 
 	var req updateReq
 	if err := c.Bind(&req); err != nil {
-		s.log.Warn("bind error", zap.Error(err))
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+		requestLog(c, s.log).Warn("bind error", zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid request")
 	}
 
 	// читаем текущую запись
-	sub, err := s.repo.GetByID(c.Request().Context(), id)
+	sub, err := s.getOwned(c, id, models.GetOptions{})
 	if err != nil {
 		if err == repository.ErrNotFound {
-			return c.JSON(http.StatusNotFound, echo.Map{"error": "not found"})
+			return errorJSON(c, http.StatusNotFound, "not found")
 		}
-		s.log.Error("get for update failed", zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to get"})
+		requestLog(c, s.log).Error("get for update failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to get")
 	}
 
 	// применяем изменения
@@ -208,8 +322,8 @@ func (s *HTTPService) Update(c echo.Context) error {
 	if req.StartDate != nil {
 		start, err := parseMonth(*req.StartDate)
 		if err != nil {
-			s.log.Warn("invalid start_date", zap.String("value", *req.StartDate), zap.Error(err))
-			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid start_date"})
+			requestLog(c, s.log).Warn("invalid start_date", zap.String("value", *req.StartDate), zap.Error(err))
+			return errorJSON(c, http.StatusBadRequest, "invalid start_date")
 		}
 		sub.StartDate = start
 	}
@@ -219,8 +333,8 @@ func (s *HTTPService) Update(c echo.Context) error {
 		} else {
 			end, err := parseMonth(*req.EndDate)
 			if err != nil {
-				s.log.Warn("invalid end_date", zap.String("value", *req.EndDate), zap.Error(err))
-				return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid end_date"})
+				requestLog(c, s.log).Warn("invalid end_date", zap.String("value", *req.EndDate), zap.Error(err))
+				return errorJSON(c, http.StatusBadRequest, "invalid end_date")
 			}
 			sub.EndDate = &end
 		}
@@ -229,17 +343,17 @@ func (s *HTTPService) Update(c echo.Context) error {
 
 	if err := s.repo.Update(c.Request().Context(), sub); err != nil {
 		if err == repository.ErrNotFound {
-			return c.JSON(http.StatusNotFound, echo.Map{"error": "not found"})
+			return errorJSON(c, http.StatusNotFound, "not found")
 		}
-		s.log.Error("update failed", zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to update"})
+		requestLog(c, s.log).Error("update failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to update")
 	}
 
 	return c.JSON(http.StatusOK, sub)
 }
 
 // @Summary Удалить подписку
-// @Description Удаляет подписку по её ID
+// @Description Помечает подписку удалённой. Её можно восстановить до истечения срока хранения, после чего она удаляется безвозвратно
 // @ID delete-subscription
 // @Tags subscriptions
 // @Accept json
@@ -248,27 +362,73 @@ func (s *HTTPService) Update(c echo.Context) error {
 // @Success 204 "Подписка удалена"
 // @Failure 400 {object} echo.Map "Неверный формат ID"
 // @Failure 404 {object} echo.Map "Подписка не найдена"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
 // @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
 // @Router /api/v1/subscriptions/{id} [delete]
 func (s *HTTPService) Delete(c echo.Context) error {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		s.log.Warn("invalid id", zap.String("id", idStr), zap.Error(err))
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid id"})
+		requestLog(c, s.log).Warn("invalid id", zap.String("id", idStr), zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid id")
+	}
+
+	// владелец подписки не меняется, поэтому проверка до удаления не устаревает
+	if _, err := s.getOwned(c, id, models.GetOptions{}); err != nil {
+		if err == repository.ErrNotFound {
+			return errorJSON(c, http.StatusNotFound, "not found")
+		}
+		requestLog(c, s.log).Error("get for delete failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to get")
 	}
 
-	if err := s.repo.Delete(c.Request().Context(), id); err != nil {
+	if _, err := s.repo.Delete(c.Request().Context(), id); err != nil {
 		if err == repository.ErrNotFound {
-			return c.JSON(http.StatusNotFound, echo.Map{"error": "not found"})
+			return errorJSON(c, http.StatusNotFound, "not found")
 		}
-		s.log.Error("delete failed", zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to delete"})
+		requestLog(c, s.log).Error("delete failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to delete")
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
+// @Summary Восстановить подписку
+// @Description Восстанавливает удалённую подписку, если она ещё не была удалена безвозвратно
+// @ID restore-subscription
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID идентификатор подписки"
+// @Success 200 {object} models.Subscription "Восстановленная подписка"
+// @Failure 400 {object} echo.Map "Неверный формат ID"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 404 {object} echo.Map "Удалённая подписка не найдена"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/subscriptions/{id}/restore [post]
+func (s *HTTPService) Restore(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		requestLog(c, s.log).Warn("invalid id", zap.String("id", idStr), zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid id")
+	}
+
+	sub, err := s.repo.Restore(c.Request().Context(), id)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return errorJSON(c, http.StatusNotFound, "not found")
+		}
+		requestLog(c, s.log).Error("restore failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to restore")
+	}
+
+	return c.JSON(http.StatusOK, sub)
+}
+
 // @Summary Список подписок
 // @Description Возвращает список подписок с фильтрацией и пагинацией
 // @ID list-subscriptions
@@ -277,54 +437,29 @@ func (s *HTTPService) Delete(c echo.Context) error {
 // @Produce json
 // @Param user_id query string false "UUID пользователя"
 // @Param service_name query string false "Название сервиса"
+// @Param include_deleted query bool false "Включать удалённые подписки (для администраторов)" default(false)
 // @Param limit query int false "Количество элементов (макс. 500)" default(50)
 // @Param offset query int false "Смещение" default(0)
+// @Param X-Read-Your-Writes header bool false "Читать с основной БД, а не с реплики" default(false)
 // @Success 200 {object} listResp "Список подписок"
 // @Failure 400 {object} echo.Map "Неверный запрос"
+// @Failure 404 {object} echo.Map "Подписки другого пользователя"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
 // @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
 // @Router /api/v1/subscriptions [get]
 func (s *HTTPService) List(c echo.Context) error {
-	var (
-		userIDPtr   *uuid.UUID
-		serviceName *string
-	)
-
-	if v := c.QueryParam("user_id"); v != "" {
-		id, err := uuid.Parse(v)
-		if err != nil {
-			s.log.Warn("invalid user_id", zap.String("value", v), zap.Error(err))
-			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user_id"})
-		}
-		userIDPtr = &id
-	}
-	if v := c.QueryParam("service_name"); v != "" {
-		serviceName = &v
-	}
-
-	limit := 50
-	offset := 0
-	if v := c.QueryParam("limit"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 500 {
-			limit = n
-		}
-	}
-	if v := c.QueryParam("offset"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
-			offset = n
-		}
+	filter, err := s.parseListFilter(c)
+	if err != nil {
+		return filterError(c, err)
 	}
 
-	filter := models.SubscriptionFilter{
-		UserID:      userIDPtr,
-		ServiceName: serviceName,
-		Limit:       limit,
-		Offset:      offset,
-	}
+	filter.Limit, filter.Offset = parsePage(c)
 
 	items, err := s.repo.List(c.Request().Context(), filter)
 	if err != nil {
-		s.log.Error("list failed", zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to list"})
+		requestLog(c, s.log).Error("list failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to list")
 	}
 
 	resp := listResp{
@@ -344,9 +479,14 @@ func (s *HTTPService) List(c echo.Context) error {
 // @Param end_period query string true "Конец периода (формат MM-YYYY)"
 // @Param user_id query string false "UUID пользователя"
 // @Param service_name query string false "Название сервиса"
+// @Param include_deleted query bool false "Учитывать удалённые подписки (для администраторов)" default(false)
+// @Param X-Read-Your-Writes header bool false "Читать с основной БД, а не с реплики" default(false)
 // @Success 200 {object} costResp "Суммарная стоимость"
 // @Failure 400 {object} echo.Map "Неверный запрос"
+// @Failure 404 {object} echo.Map "Подписки другого пользователя"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
 // @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
 // @Router /api/v1/subscriptions/cost [get]
 func (s *HTTPService) CalculateCost(c echo.Context) error {
 	var (
@@ -357,44 +497,53 @@ func (s *HTTPService) CalculateCost(c echo.Context) error {
 	startStr := c.QueryParam("start_period")
 	endStr := c.QueryParam("end_period")
 	if startStr == "" || endStr == "" {
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "start_period and end_period are required"})
+		return errorJSON(c, http.StatusBadRequest, "start_period and end_period are required")
 	}
 
 	start, err := parseMonth(startStr)
 	if err != nil {
-		s.log.Warn("invalid start_period", zap.String("value", startStr), zap.Error(err))
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid start_period"})
+		requestLog(c, s.log).Warn("invalid start_period", zap.String("value", startStr), zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid start_period")
 	}
 	end, err := parseMonth(endStr)
 	if err != nil {
-		s.log.Warn("invalid end_period", zap.String("value", endStr), zap.Error(err))
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid end_period"})
+		requestLog(c, s.log).Warn("invalid end_period", zap.String("value", endStr), zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid end_period")
 	}
 
 	if v := c.QueryParam("user_id"); v != "" {
 		id, err := uuid.Parse(v)
 		if err != nil {
-			s.log.Warn("invalid user_id", zap.String("value", v), zap.Error(err))
-			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user_id"})
+			requestLog(c, s.log).Warn("invalid user_id", zap.String("value", v), zap.Error(err))
+			return errorJSON(c, http.StatusBadRequest, "invalid user_id")
 		}
 		userIDPtr = &id
 	}
 	if v := c.QueryParam("service_name"); v != "" {
 		serviceName = &v
 	}
+	if !accessFrom(c).restrict(&userIDPtr) {
+		return errorJSON(c, http.StatusNotFound, "not found")
+	}
+
+	includeDeleted, err := parseIncludeDeleted(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
 
 	filter := models.CostFilter{
-		UserID:      userIDPtr,
-		ServiceName: serviceName,
-		StartPeriod: start,
-		EndPeriod:   end,
+		UserID:         userIDPtr,
+		ServiceName:    serviceName,
+		StartPeriod:    start,
+		EndPeriod:      end,
+		IncludeDeleted: includeDeleted,
 	}
 
 	total, err := s.repo.CalculateCost(c.Request().Context(), filter)
 	if err != nil {
-		s.log.Error("calculate cost failed", zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to calculate"})
+		requestLog(c, s.log).Error("calculate cost failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to calculate")
 	}
 
 	return c.JSON(http.StatusOK, costResp{Total: total})
-}
\ No newline at end of file
+}
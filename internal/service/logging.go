@@ -0,0 +1,79 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogLevelHTTPService - смена уровня логирования без перезапуска
+type LogLevelHTTPService struct {
+	level zap.AtomicLevel
+	log   *zap.Logger
+}
+
+func NewLogLevelHTTPService(level zap.AtomicLevel, log *zap.Logger) *LogLevelHTTPService {
+	return &LogLevelHTTPService{level: level, log: log}
+}
+
+func (s *LogLevelHTTPService) RegisterRoutes(e *echo.Echo) {
+	g := e.Group("/api/v1/admin/log-level", RequireAdmin)
+	g.GET("", s.Get)
+	g.PUT("", s.Set)
+}
+
+// DTOs
+
+// swagger:model logLevel
+type logLevel struct {
+	Level string `json:"level"` // debug, info, warn, error
+}
+
+// Handlers
+
+// @Summary Текущий уровень логирования
+// @ID get-log-level
+// @Tags admin
+// @Produce json
+// @Success 200 {object} logLevel "Уровень логирования"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Security BearerAuth
+// @Router /api/v1/admin/log-level [get]
+func (s *LogLevelHTTPService) Get(c echo.Context) error {
+	return c.JSON(http.StatusOK, logLevel{Level: s.level.String()})
+}
+
+// @Summary Изменить уровень логирования
+// @Description Меняет уровень логирования экземпляра сервиса до перезапуска. Уровень из конфигурации восстанавливается при следующем запуске
+// @ID set-log-level
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param input body logLevel true "Новый уровень"
+// @Success 200 {object} logLevel "Установленный уровень"
+// @Failure 400 {object} echo.Map "Неверный запрос"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Security BearerAuth
+// @Router /api/v1/admin/log-level [put]
+func (s *LogLevelHTTPService) Set(c echo.Context) error {
+	var req logLevel
+	if err := c.Bind(&req); err != nil {
+		requestLog(c, s.log).Warn("bind error", zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid request")
+	}
+
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil || level > zapcore.ErrorLevel {
+		return errorJSON(c, http.StatusBadRequest, "invalid level")
+	}
+
+	previous := s.level.Level()
+	s.level.SetLevel(level)
+	requestLog(c, s.log).Warn("log level changed", zap.Stringer("from", previous), zap.Stringer("to", level))
+
+	return c.JSON(http.StatusOK, logLevel{Level: level.String()})
+}
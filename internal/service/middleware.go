@@ -0,0 +1,274 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/auth"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+	"github.com/untibullet/subscription-service-em/internal/tracing"
+	"go.uber.org/zap"
+)
+
+// HeaderActor - заголовок с идентификатором инициатора изменений
+const HeaderActor = "X-Actor"
+
+// HeaderReadYourWrites - заголовок, требующий читать данные с основной БД,
+// а не с реплики (true), например сразу после изменения подписки
+const HeaderReadYourWrites = "X-Read-Your-Writes"
+
+// requestIDPattern - допустимый входящий X-Request-ID. Идентификатор попадает
+// в логи и комментарии SQL, поэтому произвольные символы не принимаются
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestContext переносит в контекст запроса данные для журнала изменений:
+// идентификатор запроса и инициатора, а также требование читать с основной
+// БД. Идентификатор берется из X-Request-ID или генерируется, если заголовка
+// нет или он недопустим, и возвращается в ответе
+func RequestContext() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := req.Context()
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if !requestIDPattern.MatchString(id) {
+				id = uuid.NewString()
+			}
+			ctx = reqctx.WithRequestID(ctx, id)
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+			if actor := req.Header.Get(HeaderActor); actor != "" {
+				ctx = reqctx.WithActor(ctx, actor)
+			}
+			if v, _ := strconv.ParseBool(req.Header.Get(HeaderReadYourWrites)); v {
+				ctx = reqctx.WithReadYourWrites(ctx)
+			}
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// Authentication требует на всех путях, кроме начинающихся с publicPaths,
+// bearer-JWT или API-ключ (Authorization: ApiKey <ключ>) и сохраняет клиента
+// в контексте запроса. Субъект становится инициатором изменений вместо
+// заголовка X-Actor. authn или keys может быть nil, если способ выключен
+func Authentication(authn *auth.Authenticator, keys *auth.APIKeyVerifier, log *zap.Logger, publicPaths ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			for _, p := range publicPaths {
+				if strings.HasPrefix(req.URL.Path, p) {
+					return next(c)
+				}
+			}
+
+			scheme, credentials, _ := strings.Cut(req.Header.Get(echo.HeaderAuthorization), " ")
+			credentials = strings.TrimSpace(credentials)
+
+			var (
+				principal *auth.Principal
+				err       error
+			)
+			switch {
+			case credentials == "":
+				err = auth.ErrUnauthenticated
+			case authn != nil && strings.EqualFold(scheme, "Bearer"):
+				principal, err = authn.Authenticate(req.Context(), credentials)
+			case keys != nil && strings.EqualFold(scheme, "ApiKey"):
+				principal, err = keys.Verify(req.Context(), credentials)
+			default:
+				err = auth.ErrUnauthenticated
+			}
+
+			if err != nil {
+				if errors.Is(err, auth.ErrKeysUnavailable) {
+					log.Error("authentication keys unavailable", zap.Error(err))
+					return errorJSON(c, http.StatusServiceUnavailable, "authentication is temporarily unavailable")
+				}
+				if !errors.Is(err, auth.ErrUnauthenticated) {
+					log.Error("authentication error", zap.Error(err))
+					return errorJSON(c, http.StatusInternalServerError, "failed to authenticate")
+				}
+				if credentials == "" {
+					c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="api"`)
+					return errorJSON(c, http.StatusUnauthorized, "missing credentials")
+				}
+				log.Warn("authentication failed", zap.String("path", req.URL.Path), zap.Error(err))
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="api", error="invalid_token"`)
+				return errorJSON(c, http.StatusUnauthorized, "invalid credentials")
+			}
+
+			ctx := auth.WithPrincipal(req.Context(), principal)
+			ctx = reqctx.WithActor(ctx, principal.Subject)
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// ClientCertificate сохраняет в контексте запроса клиента mTLS и требует
+// проверенный сертификат клиента на всех путях, кроме начинающихся с
+// publicPaths. Сертификат проверяется при TLS-рукопожатии, но там не
+// требуется, чтобы проверки оркестратора проходили без него
+func ClientCertificate(log *zap.Logger, publicPaths ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			cert := auth.NewClientCert(req.TLS)
+			if cert != nil {
+				c.SetRequest(req.WithContext(auth.WithClientCert(req.Context(), cert)))
+				return next(c)
+			}
+
+			for _, p := range publicPaths {
+				if strings.HasPrefix(req.URL.Path, p) {
+					return next(c)
+				}
+			}
+			log.Warn("client certificate missing", zap.String("path", req.URL.Path), zap.String("remote_ip", c.RealIP()))
+			return errorJSON(c, http.StatusUnauthorized, "client certificate required")
+		}
+	}
+}
+
+// HeaderTenant - заголовок с организацией, если аутентификация выключена
+const HeaderTenant = "X-Tenant-ID"
+
+// tenantPattern - допустимый идентификатор организации
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Tenancy определяет организацию запроса. Аутентифицированный клиент работает
+// в организации из токена или API-ключа, заголовок header при этом игнорируется;
+// токен без организации отклоняется, чтобы ошибка настройки провайдера не
+// объединила разные организации в одну. Без аутентификации организация
+// берется из заголовка header, по умолчанию - reqctx.DefaultTenant
+func Tenancy(header string, log *zap.Logger) echo.MiddlewareFunc {
+	if header == "" {
+		header = HeaderTenant
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			var tenant string
+			if p := auth.FromContext(req.Context()); p != nil {
+				if p.TenantID == "" {
+					log.Warn("token has no tenant", zap.String("subject", p.Subject))
+					return errorJSON(c, http.StatusForbidden, "token has no tenant")
+				}
+				tenant = p.TenantID
+			} else if tenant = req.Header.Get(header); tenant == "" {
+				tenant = reqctx.DefaultTenant
+			}
+			if !tenantPattern.MatchString(tenant) {
+				log.Warn("invalid tenant", zap.String("tenant", tenant))
+				return errorJSON(c, http.StatusBadRequest, "invalid tenant")
+			}
+
+			c.SetRequest(req.WithContext(reqctx.WithTenant(req.Context(), tenant)))
+			return next(c)
+		}
+	}
+}
+
+// requestLog добавляет к логгеру идентификатор запроса и трассы
+func requestLog(c echo.Context, log *zap.Logger) *zap.Logger {
+	ctx := c.Request().Context()
+	if id := reqctx.RequestID(ctx); id != "" {
+		log = log.With(zap.String("request_id", id))
+	}
+	return tracing.Logger(ctx, log)
+}
+
+// errorJSON отвечает ошибкой с идентификатором запроса, по которому ее можно
+// найти в логах
+func errorJSON(c echo.Context, code int, message string) error {
+	return c.JSON(code, echo.Map{"error": message, "request_id": c.Response().Header().Get(echo.HeaderXRequestID)})
+}
+
+// HTTPErrorHandler отвечает на ошибки, которые не обработали ручки (неизвестный
+// маршрут, неподдерживаемый метод, паника), в том же формате, что и ручки
+func HTTPErrorHandler(log *zap.Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+
+		code := http.StatusInternalServerError
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			code = he.Code
+		}
+
+		if c.Request().Method == http.MethodHead {
+			err = c.NoContent(code)
+		} else {
+			err = errorJSON(c, code, strings.ToLower(http.StatusText(code)))
+		}
+		if err != nil {
+			requestLog(c, log).Error("write error response failed", zap.Error(err))
+		}
+	}
+}
+
+// RequestLogger пишет по строке на запрос: метод, маршрут, статус, длительность
+// и клиента. Ошибку обработчика сразу превращает в ответ,
+// чтобы в лог попал итоговый статус. Запросы к skipPaths не логируются
+func RequestLogger(log *zap.Logger, skipPaths ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			for _, p := range skipPaths {
+				if c.Request().URL.Path == p {
+					return next(c)
+				}
+			}
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			// внутренние middleware заменяют запрос, контекст берется после обработки
+			req := c.Request()
+			res := c.Response()
+			fields := []zap.Field{
+				zap.String("method", req.Method),
+				zap.String("route", c.Path()),
+				zap.String("uri", req.RequestURI),
+				zap.Int("status", res.Status),
+				zap.Duration("latency", time.Since(start)),
+				zap.Int64("bytes_out", res.Size),
+				zap.String("remote_ip", c.RealIP()),
+			}
+			fields = append(fields, zap.String("user", reqctx.Actor(req.Context())))
+			if tenant, ok := reqctx.TenantFromContext(req.Context()); ok {
+				fields = append(fields, zap.String("tenant", tenant))
+			}
+			if cert := auth.ClientCertFromContext(req.Context()); cert != nil {
+				fields = append(fields, zap.String("client_cert", cert.Subject))
+			}
+			if err != nil {
+				fields = append(fields, zap.Error(err))
+			}
+
+			l := requestLog(c, log)
+			switch {
+			case res.Status >= http.StatusInternalServerError:
+				l.Error("request", fields...)
+			case res.Status >= http.StatusBadRequest:
+				l.Warn("request", fields...)
+			default:
+				l.Info("request", fields...)
+			}
+			// ошибка уже обработана, обработчик echo ее пропустит
+			return err
+		}
+	}
+}
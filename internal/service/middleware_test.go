@@ -0,0 +1,64 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/auth"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+	"go.uber.org/zap"
+)
+
+func TestTenancy(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		header     string
+		wantStatus int
+		wantTenant string
+	}{
+		{name: "anonymous without header", wantStatus: http.StatusOK, wantTenant: reqctx.DefaultTenant},
+		{name: "anonymous with header", header: "acme", wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "invalid header", header: "Acme Corp", wantStatus: http.StatusBadRequest},
+		{name: "tenant from token", principal: &auth.Principal{TenantID: "acme"}, wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "token ignores header", principal: &auth.Principal{TenantID: "acme"}, header: "other", wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "token without tenant", principal: &auth.Principal{Subject: "user"}, header: "acme", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			if tt.principal != nil {
+				e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+					return func(c echo.Context) error {
+						c.SetRequest(c.Request().WithContext(auth.WithPrincipal(c.Request().Context(), tt.principal)))
+						return next(c)
+					}
+				})
+			}
+			e.Use(Tenancy(HeaderTenant, zap.NewNop()))
+
+			var tenant string
+			e.GET("/", func(c echo.Context) error {
+				tenant = reqctx.Tenant(c.Request().Context())
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderTenant, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tenant != tt.wantTenant {
+				t.Errorf("tenant = %q, want %q", tenant, tt.wantTenant)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/auth"
+	"github.com/untibullet/subscription-service-em/internal/ratelimit"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+	"go.uber.org/zap"
+)
+
+// reportPaths - тяжелые отчеты, для которых действует самый строгий лимит
+var reportPaths = map[string]bool{
+	"/api/v1/subscriptions/cost":   true,
+	"/api/v1/subscriptions/export": true,
+}
+
+// rateLimitGroup относит маршрут к группе лимитов: отчеты, изменения или чтение
+func rateLimitGroup(c echo.Context) ratelimit.Group {
+	switch {
+	case reportPaths[c.Path()]:
+		return ratelimit.GroupReports
+	case c.Request().Method == http.MethodGet || c.Request().Method == http.MethodHead:
+		return ratelimit.GroupRead
+	default:
+		return ratelimit.GroupWrite
+	}
+}
+
+// rateLimitClient - клиент, которому принадлежит корзина: API-ключ или
+// пользователь из токена, а без аутентификации - IP-адрес
+func rateLimitClient(c echo.Context) string {
+	ctx := c.Request().Context()
+	if p := auth.FromContext(ctx); p != nil {
+		return reqctx.Tenant(ctx) + "/" + p.Subject
+	}
+	return "ip:" + c.RealIP()
+}
+
+// RateLimit ограничивает частоту запросов клиента на всех путях, кроме
+// начинающихся с publicPaths, и сообщает состояние лимита в заголовках
+// RateLimit-*. При ошибке хранилища запрос пропускается, чтобы сбой лимитов
+// не останавливал API
+func RateLimit(limiter *ratelimit.Limiter, log *zap.Logger, publicPaths ...string) echo.MiddlewareFunc {
+	return rateLimit(limiter, rateLimitGroup, rateLimitClient, log, publicPaths)
+}
+
+// IPRateLimit ограничивает частоту всех запросов с IP-адреса клиента так же,
+// как RateLimit. Ставится до аутентификации, чтобы ограничить и поток
+// запросов с неверными учетными данными
+func IPRateLimit(limiter *ratelimit.Limiter, log *zap.Logger, publicPaths ...string) echo.MiddlewareFunc {
+	group := func(echo.Context) ratelimit.Group { return ratelimit.GroupIP }
+	return rateLimit(limiter, group, echo.Context.RealIP, log, publicPaths)
+}
+
+func rateLimit(
+	limiter *ratelimit.Limiter,
+	groupOf func(echo.Context) ratelimit.Group,
+	clientOf func(echo.Context) string,
+	log *zap.Logger,
+	publicPaths []string,
+) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			for _, p := range publicPaths {
+				if strings.HasPrefix(req.URL.Path, p) {
+					return next(c)
+				}
+			}
+
+			group, client := groupOf(c), clientOf(c)
+			res, err := limiter.Allow(req.Context(), group, client)
+			if err != nil {
+				log.Error("rate limit check failed", zap.String("group", string(group)), zap.Error(err))
+				return next(c)
+			}
+			if res.Limit == 0 {
+				return next(c)
+			}
+
+			h := c.Response().Header()
+			h.Set("RateLimit-Policy", limiter.Policy(group))
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", seconds(res.Reset))
+
+			if !res.Allowed {
+				h.Set(echo.HeaderRetryAfter, seconds(res.RetryAfter))
+				log.Warn("rate limit exceeded",
+					zap.String("group", string(group)),
+					zap.String("client", client),
+					zap.String("path", c.Path()),
+				)
+				return errorJSON(c, http.StatusTooManyRequests, "rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
+
+// seconds округляет длительность вверх до целых секунд
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
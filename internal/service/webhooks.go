@@ -0,0 +1,409 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"go.uber.org/zap"
+)
+
+// minWebhookSecretLen - минимальная длина секрета для подписи HMAC
+const minWebhookSecretLen = 16
+
+// WebhookHTTPService - HTTP-слой управления получателями событий
+type WebhookHTTPService struct {
+	repo repository.WebhookRepository
+	log  *zap.Logger
+}
+
+func NewWebhookHTTPService(repo repository.WebhookRepository, log *zap.Logger) *WebhookHTTPService {
+	return &WebhookHTTPService{repo: repo, log: log}
+}
+
+func (s *WebhookHTTPService) RegisterRoutes(e *echo.Echo) {
+	// получатели видят события всех пользователей, поэтому управлять ими может только администратор
+	g := e.Group("/api/v1/webhooks", RequireAdmin)
+	g.POST("", s.Create)
+	g.GET("", s.List)
+	g.GET("/:id", s.GetByID)
+	g.PUT("/:id", s.Update)
+	g.DELETE("/:id", s.Delete)
+	g.GET("/:id/deliveries", s.Deliveries)
+	g.POST("/:id/deliveries/:delivery_id/redeliver", s.Redeliver)
+}
+
+// DTOs
+
+// swagger:model CreateWebhookRequest
+type createWebhookReq struct {
+	URL        string             `json:"url"`
+	Secret     string             `json:"secret"`
+	EventTypes []models.EventType `json:"event_types"`
+}
+
+// swagger:model UpdateWebhookRequest
+type updateWebhookReq struct {
+	URL        *string            `json:"url,omitempty"`
+	Secret     *string            `json:"secret,omitempty"`
+	EventTypes []models.EventType `json:"event_types,omitempty"`
+	Active     *bool              `json:"active,omitempty"`
+}
+
+// swagger:model webhookListResp
+type webhookListResp struct {
+	Data  []*models.Webhook `json:"data"`
+	Total int               `json:"total"`
+}
+
+// swagger:model deliveryListResp
+type deliveryListResp struct {
+	Data  []*models.WebhookDelivery `json:"data"`
+	Total int                       `json:"total"`
+}
+
+// Helpers
+
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("invalid url")
+	}
+	return nil
+}
+
+func validateEventTypes(types []models.EventType) error {
+	if len(types) == 0 {
+		return errors.New("event_types is required")
+	}
+	for _, t := range types {
+		if !t.Valid() {
+			return errors.New("unknown event type " + string(t))
+		}
+	}
+	return nil
+}
+
+func (s *WebhookHTTPService) parseID(c echo.Context, name string) (uuid.UUID, error) {
+	v := c.Param(name)
+	id, err := uuid.Parse(v)
+	if err != nil {
+		requestLog(c, s.log).Warn("invalid "+name, zap.String(name, v), zap.Error(err))
+		return uuid.Nil, errors.New("invalid " + name)
+	}
+	return id, nil
+}
+
+// Handlers
+
+// @Summary Зарегистрировать получателя событий
+// @Description Регистрирует URL, на который будут доставляться события выбранных типов. Тело каждой доставки подписывается HMAC-SHA256 на секрете получателя (заголовок X-Webhook-Signature)
+// @ID create-webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param input body createWebhookReq true "Данные получателя"
+// @Success 201 {object} models.Webhook "Зарегистрированный получатель"
+// @Failure 400 {object} echo.Map "Неверный запрос"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 409 {object} echo.Map "Получатель с таким URL уже зарегистрирован"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/webhooks [post]
+func (s *WebhookHTTPService) Create(c echo.Context) error {
+	var req createWebhookReq
+	if err := c.Bind(&req); err != nil {
+		requestLog(c, s.log).Warn("bind error", zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid request")
+	}
+
+	if err := validateWebhookURL(req.URL); err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	if len(req.Secret) < minWebhookSecretLen {
+		return errorJSON(c, http.StatusBadRequest, "secret must be at least 16 characters")
+	}
+	if err := validateEventTypes(req.EventTypes); err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	now := time.Now().UTC()
+	wh := models.Webhook{
+		ID:         uuid.New(),
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+		Active:     true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if err := s.repo.Create(c.Request().Context(), &wh); err != nil {
+		if errors.Is(err, repository.ErrWebhookExists) {
+			return errorJSON(c, http.StatusConflict, "webhook already exists")
+		}
+		requestLog(c, s.log).Error("create webhook failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to create")
+	}
+
+	return c.JSON(http.StatusCreated, wh)
+}
+
+// @Summary Список получателей событий
+// @Description Возвращает всех зарегистрированных получателей (без секретов)
+// @ID list-webhooks
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Success 200 {object} webhookListResp "Список получателей"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/webhooks [get]
+func (s *WebhookHTTPService) List(c echo.Context) error {
+	items, err := s.repo.List(c.Request().Context())
+	if err != nil {
+		requestLog(c, s.log).Error("list webhooks failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to list")
+	}
+
+	return c.JSON(http.StatusOK, webhookListResp{Data: items, Total: len(items)})
+}
+
+// @Summary Получить получателя событий
+// @Description Возвращает получателя событий по ID (без секрета)
+// @ID get-webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID получателя"
+// @Success 200 {object} models.Webhook "Получатель"
+// @Failure 400 {object} echo.Map "Неверный формат ID"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 404 {object} echo.Map "Получатель не найден"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id} [get]
+func (s *WebhookHTTPService) GetByID(c echo.Context) error {
+	id, err := s.parseID(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	wh, err := s.repo.GetByID(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return errorJSON(c, http.StatusNotFound, "not found")
+		}
+		requestLog(c, s.log).Error("get webhook failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to get")
+	}
+
+	return c.JSON(http.StatusOK, wh)
+}
+
+// @Summary Обновить получателя событий
+// @Description Меняет URL, секрет, типы событий или активность получателя. Отключенному получателю новые события не доставляются, а ожидающие доставки уходят в dead letter
+// @ID update-webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID получателя"
+// @Param input body updateWebhookReq true "Данные для обновления"
+// @Success 200 {object} models.Webhook "Обновлённый получатель"
+// @Failure 400 {object} echo.Map "Неверный запрос"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 404 {object} echo.Map "Получатель не найден"
+// @Failure 409 {object} echo.Map "Получатель с таким URL уже зарегистрирован"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id} [put]
+func (s *WebhookHTTPService) Update(c echo.Context) error {
+	id, err := s.parseID(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var req updateWebhookReq
+	if err := c.Bind(&req); err != nil {
+		requestLog(c, s.log).Warn("bind error", zap.Error(err))
+		return errorJSON(c, http.StatusBadRequest, "invalid request")
+	}
+
+	wh, err := s.repo.GetByID(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return errorJSON(c, http.StatusNotFound, "not found")
+		}
+		requestLog(c, s.log).Error("get webhook for update failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to get")
+	}
+
+	if req.URL != nil {
+		if err := validateWebhookURL(*req.URL); err != nil {
+			return errorJSON(c, http.StatusBadRequest, err.Error())
+		}
+		wh.URL = *req.URL
+	}
+	if req.Secret != nil {
+		if len(*req.Secret) < minWebhookSecretLen {
+			return errorJSON(c, http.StatusBadRequest, "secret must be at least 16 characters")
+		}
+		wh.Secret = *req.Secret
+	}
+	if req.EventTypes != nil {
+		if err := validateEventTypes(req.EventTypes); err != nil {
+			return errorJSON(c, http.StatusBadRequest, err.Error())
+		}
+		wh.EventTypes = req.EventTypes
+	}
+	if req.Active != nil {
+		wh.Active = *req.Active
+	}
+	wh.UpdatedAt = time.Now().UTC()
+
+	if err := s.repo.Update(c.Request().Context(), wh); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return errorJSON(c, http.StatusNotFound, "not found")
+		}
+		if errors.Is(err, repository.ErrWebhookExists) {
+			return errorJSON(c, http.StatusConflict, "webhook already exists")
+		}
+		requestLog(c, s.log).Error("update webhook failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to update")
+	}
+
+	return c.JSON(http.StatusOK, wh)
+}
+
+// @Summary Удалить получателя событий
+// @Description Удаляет получателя вместе с журналом доставок
+// @ID delete-webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID получателя"
+// @Success 204 "Получатель удалён"
+// @Failure 400 {object} echo.Map "Неверный формат ID"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 404 {object} echo.Map "Получатель не найден"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id} [delete]
+func (s *WebhookHTTPService) Delete(c echo.Context) error {
+	id, err := s.parseID(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := s.repo.Delete(c.Request().Context(), id); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return errorJSON(c, http.StatusNotFound, "not found")
+		}
+		requestLog(c, s.log).Error("delete webhook failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to delete")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Журнал доставок
+// @Description Возвращает доставки событий получателю: статус, число попыток, последнюю ошибку и код ответа
+// @ID list-webhook-deliveries
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID получателя"
+// @Param status query string false "Статус доставки" Enums(pending, delivered, dead)
+// @Param limit query int false "Количество элементов (макс. 500)" default(50)
+// @Param offset query int false "Смещение" default(0)
+// @Success 200 {object} deliveryListResp "Журнал доставок"
+// @Failure 400 {object} echo.Map "Неверный запрос"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 404 {object} echo.Map "Получатель не найден"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id}/deliveries [get]
+func (s *WebhookHTTPService) Deliveries(c echo.Context) error {
+	id, err := s.parseID(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	filter := models.DeliveryFilter{WebhookID: id}
+	if v := c.QueryParam("status"); v != "" {
+		status := models.DeliveryStatus(v)
+		switch status {
+		case models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+			filter.Status = &status
+		default:
+			return errorJSON(c, http.StatusBadRequest, "invalid status")
+		}
+	}
+	filter.Limit, filter.Offset = parsePage(c)
+
+	if _, err := s.repo.GetByID(c.Request().Context(), id); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return errorJSON(c, http.StatusNotFound, "not found")
+		}
+		requestLog(c, s.log).Error("get webhook failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to get")
+	}
+
+	items, err := s.repo.ListDeliveries(c.Request().Context(), filter)
+	if err != nil {
+		requestLog(c, s.log).Error("list webhook deliveries failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to list")
+	}
+
+	return c.JSON(http.StatusOK, deliveryListResp{Data: items, Total: len(items)})
+}
+
+// @Summary Повторить доставку из dead letter
+// @Description Возвращает доставку в статусе dead в очередь с обнулением счётчика попыток
+// @ID redeliver-webhook-delivery
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID получателя"
+// @Param delivery_id path string true "UUID доставки"
+// @Success 200 {object} models.WebhookDelivery "Доставка, поставленная в очередь"
+// @Failure 400 {object} echo.Map "Неверный формат ID"
+// @Failure 403 {object} echo.Map "Требуется роль администратора"
+// @Failure 404 {object} echo.Map "Доставка в статусе dead не найдена"
+// @Failure 429 {object} echo.Map "Превышен лимит запросов"
+// @Failure 500 {object} echo.Map "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (s *WebhookHTTPService) Redeliver(c echo.Context) error {
+	id, err := s.parseID(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	deliveryID, err := s.parseID(c, "delivery_id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	d, err := s.repo.Redeliver(c.Request().Context(), id, deliveryID)
+	if err != nil {
+		if errors.Is(err, repository.ErrDeliveryNotFound) {
+			return errorJSON(c, http.StatusNotFound, "not found")
+		}
+		requestLog(c, s.log).Error("redeliver failed", zap.Error(err))
+		return errorJSON(c, http.StatusInternalServerError, "failed to redeliver")
+	}
+
+	return c.JSON(http.StatusOK, d)
+}
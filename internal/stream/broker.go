@@ -0,0 +1,242 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// streamedTypes - события, которые попадают в поток
+var streamedTypes = map[models.EventType]bool{
+	models.EventSubscriptionCreated: true,
+	models.EventSubscriptionUpdated: true,
+	models.EventSubscriptionDeleted: true,
+}
+
+// Filter - условия отбора событий для подписчика. nil - без ограничения
+type Filter struct {
+	TenantID    string // организация подписчика; события других организаций не отдаются
+	UserID      *uuid.UUID
+	ServiceName *string
+}
+
+func (f Filter) match(e entry) bool {
+	if f.TenantID != e.event.Event.TenantID {
+		return false
+	}
+	if f.UserID != nil && *f.UserID != e.userID {
+		return false
+	}
+	if f.ServiceName != nil && *f.ServiceName != e.serviceName {
+		return false
+	}
+	return true
+}
+
+// entry - событие с полями подписки, по которым фильтруется поток
+type entry struct {
+	event       models.StreamEvent
+	userID      uuid.UUID
+	serviceName string
+}
+
+// Broker получает события всех экземпляров сервиса через EventListener,
+// хранит последние replaySize событий для возобновления потока и раздает
+// события подписчикам
+type Broker struct {
+	listener     repository.EventListener
+	replaySize   int
+	clientBuffer int
+	log          *zap.Logger
+
+	mu     sync.Mutex
+	replay []entry // кольцевой буфер в порядке получения
+	head   int     // позиция самого старого события, когда буфер заполнен
+	subs   map[*Subscription]struct{}
+	down   error // ошибка соединения, пока брокер ждет переподключения
+}
+
+func NewBroker(listener repository.EventListener, replaySize, clientBuffer int, log *zap.Logger) *Broker {
+	return &Broker{
+		listener:     listener,
+		replaySize:   replaySize,
+		clientBuffer: clientBuffer,
+		log:          log,
+		replay:       make([]entry, 0, replaySize),
+		subs:         make(map[*Subscription]struct{}),
+	}
+}
+
+// Run слушает события до отмены ctx, переподключаясь при обрыве соединения.
+// События, записанные во время переподключения, в поток не попадают
+func (b *Broker) Run(ctx context.Context) {
+	b.log.Info("event stream broker started", zap.Int("replay_size", b.replaySize))
+
+	delay := minReconnectDelay
+	for {
+		b.setDown(nil)
+		started := time.Now()
+		err := b.listener.Listen(ctx, b.publish)
+		if ctx.Err() != nil {
+			b.closeAll()
+			b.log.Info("event stream broker stopped")
+			return
+		}
+
+		// соединение жило достаточно долго - начинаем отсчет задержки заново
+		if time.Since(started) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+		b.log.Warn("event listener disconnected", zap.Duration("retry_in", delay), zap.Error(err))
+		b.setDown(err)
+
+		select {
+		case <-ctx.Done():
+			b.closeAll()
+			b.log.Info("event stream broker stopped")
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+func (b *Broker) setDown(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.down = err
+}
+
+// Check сообщает, слушает ли брокер события
+func (b *Broker) Check(context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.down != nil {
+		return fmt.Errorf("event listener disconnected: %w", b.down)
+	}
+	return nil
+}
+
+// publish сохраняет событие в буфер и раздает подписчикам. Подписчик, который
+// не успевает читать, отключается и может продолжить с Last-Event-ID
+func (b *Broker) publish(event models.StreamEvent) {
+	if !streamedTypes[event.Event.Type] {
+		return
+	}
+
+	var sub models.Subscription
+	if err := json.Unmarshal(event.Event.Data, &sub); err != nil {
+		b.log.Warn("invalid event data", zap.String("event_id", event.Event.ID), zap.Error(err))
+		return
+	}
+	e := entry{event: event, userID: sub.UserID, serviceName: sub.ServiceName}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.replaySize > 0 {
+		if len(b.replay) < b.replaySize {
+			b.replay = append(b.replay, e)
+		} else {
+			b.replay[b.head] = e
+			b.head = (b.head + 1) % b.replaySize
+		}
+	}
+
+	for s := range b.subs {
+		if !s.filter.match(e) {
+			continue
+		}
+		select {
+		case s.ch <- event:
+		default:
+			b.log.Warn("event stream client too slow, disconnecting", zap.Int64("seq", event.Seq))
+			b.remove(s)
+		}
+	}
+}
+
+// Subscribe регистрирует подписчика. Если lastSeq больше нуля, возвращает
+// события из буфера, пришедшие после события lastSeq и подходящие под фильтр
+func (b *Broker) Subscribe(filter Filter, lastSeq int64) ([]models.StreamEvent, *Subscription) {
+	ch := make(chan models.StreamEvent, b.clientBuffer)
+	s := &Subscription{C: ch, ch: ch, filter: filter, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subs[s] = struct{}{}
+	if lastSeq <= 0 {
+		return nil, s
+	}
+
+	ordered := make([]entry, 0, len(b.replay))
+	ordered = append(ordered, b.replay[b.head:]...)
+	ordered = append(ordered, b.replay[:b.head]...)
+
+	// номера выдаются при записи, а приходят в порядке фиксации транзакций,
+	// поэтому сначала ищем само событие lastSeq и отдаем все после него
+	start := -1
+	for i, e := range ordered {
+		if e.event.Seq == lastSeq {
+			start = i + 1
+			break
+		}
+	}
+
+	replay := make([]models.StreamEvent, 0)
+	for i, e := range ordered {
+		if start >= 0 && i < start || start < 0 && e.event.Seq <= lastSeq {
+			continue
+		}
+		if filter.match(e) {
+			replay = append(replay, e.event)
+		}
+	}
+
+	return replay, s
+}
+
+// remove отключает подписчика. Вызывается под b.mu
+func (b *Broker) remove(s *Subscription) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+func (b *Broker) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		b.remove(s)
+	}
+}
+
+// Subscription - подписка на поток событий. Канал C закрывается, когда
+// брокер отключает подписчика
+type Subscription struct {
+	C      <-chan models.StreamEvent
+	ch     chan models.StreamEvent
+	filter Filter
+	broker *Broker
+}
+
+// Close отписывает подписчика от потока
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
@@ -0,0 +1,135 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"go.uber.org/zap"
+)
+
+var (
+	alice = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	bob   = uuid.MustParse("22222222-2222-2222-2222-222222222222")
+)
+
+func streamEvent(t *testing.T, seq int64, tenantID string, userID uuid.UUID, service string) models.StreamEvent {
+	t.Helper()
+	sub := models.Subscription{ID: uuid.New(), UserID: userID, ServiceName: service}
+	event, err := models.NewEvent(uuid.NewString(), models.EventSubscriptionCreated, tenantID, sub.ID, sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models.StreamEvent{Seq: seq, Event: event}
+}
+
+func seqs(events []models.StreamEvent) []int64 {
+	out := make([]int64, 0, len(events))
+	for _, e := range events {
+		out = append(out, e.Seq)
+	}
+	return out
+}
+
+func TestBrokerReplay(t *testing.T) {
+	netflix := "Netflix"
+
+	tests := []struct {
+		name       string
+		replaySize int
+		published  []int64 // номера событий в порядке получения
+		lastSeq    int64
+		filter     Filter
+		want       []int64
+	}{
+		{name: "without Last-Event-ID", replaySize: 10, published: []int64{1, 2, 3}, lastSeq: 0, want: nil},
+		{name: "events after last", replaySize: 10, published: []int64{1, 2, 3, 4}, lastSeq: 2, want: []int64{3, 4}},
+		{name: "up to date", replaySize: 10, published: []int64{1, 2, 3}, lastSeq: 3, want: []int64{}},
+		{name: "ring buffer keeps newest", replaySize: 3, published: []int64{1, 2, 3, 4, 5}, lastSeq: 3, want: []int64{4, 5}},
+		{name: "last event already evicted", replaySize: 3, published: []int64{1, 2, 3, 4, 5}, lastSeq: 1, want: []int64{3, 4, 5}},
+		// 5 зафиксирован раньше 4: после события 5 отдается и 4
+		{name: "out of order commits", replaySize: 10, published: []int64{1, 2, 3, 5, 4}, lastSeq: 5, want: []int64{4}},
+		{name: "unknown last event", replaySize: 10, published: []int64{1, 2, 5, 4}, lastSeq: 3, want: []int64{5, 4}},
+		{name: "replay disabled", replaySize: 0, published: []int64{1, 2, 3}, lastSeq: 1, want: []int64{}},
+		{name: "filter by user", replaySize: 10, published: []int64{1, 2, 3, 4}, lastSeq: 1, filter: Filter{UserID: &bob}, want: []int64{2, 4}},
+		{name: "filter by service", replaySize: 10, published: []int64{1, 2, 3, 4}, lastSeq: 1, filter: Filter{ServiceName: &netflix}, want: []int64{3}},
+		{name: "other tenant", replaySize: 10, published: []int64{1, 2, 3}, lastSeq: 1, filter: Filter{TenantID: "other"}, want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBroker(nil, tt.replaySize, 10, zap.NewNop())
+			for _, seq := range tt.published {
+				// четные события - bob, события 3 - Netflix
+				user, service := alice, "Spotify"
+				if seq%2 == 0 {
+					user = bob
+				}
+				if seq == 3 {
+					service = netflix
+				}
+				b.publish(streamEvent(t, seq, "", user, service))
+			}
+
+			replay, sub := b.Subscribe(tt.filter, tt.lastSeq)
+			defer sub.Close()
+
+			if tt.want == nil {
+				if replay != nil {
+					t.Fatalf("replay = %v, want nil", seqs(replay))
+				}
+				return
+			}
+			got := seqs(replay)
+			if len(got) != len(tt.want) {
+				t.Fatalf("replay = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("replay = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBrokerPublish(t *testing.T) {
+	b := NewBroker(nil, 10, 1, zap.NewNop())
+
+	mine, sub := b.Subscribe(Filter{TenantID: "acme"}, 0)
+	if mine != nil {
+		t.Fatalf("replay = %v, want nil", seqs(mine))
+	}
+	other, otherSub := b.Subscribe(Filter{TenantID: "other"}, 0)
+	defer otherSub.Close()
+	if other != nil {
+		t.Fatalf("replay = %v, want nil", seqs(other))
+	}
+
+	b.publish(streamEvent(t, 1, "acme", alice, "Netflix"))
+	if e := <-sub.C; e.Seq != 1 {
+		t.Fatalf("received seq %d, want 1", e.Seq)
+	}
+	select {
+	case e := <-otherSub.C:
+		t.Fatalf("event of another tenant delivered: %d", e.Seq)
+	default:
+	}
+
+	// буфер клиента на одно событие: второе непрочитанное отключает его
+	b.publish(streamEvent(t, 2, "acme", alice, "Netflix"))
+	b.publish(streamEvent(t, 3, "acme", alice, "Netflix"))
+	if e := <-sub.C; e.Seq != 2 {
+		t.Fatalf("received seq %d, want 2", e.Seq)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Fatal("slow subscriber was not disconnected")
+	}
+
+	// переподключение с Last-Event-ID отдает пропущенное
+	replay, sub := b.Subscribe(Filter{TenantID: "acme"}, 2)
+	defer sub.Close()
+	if got := seqs(replay); len(got) != 1 || got[0] != 3 {
+		t.Fatalf("replay = %v, want [3]", got)
+	}
+}
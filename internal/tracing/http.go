@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/untibullet/subscription-service-em/internal/httputil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware открывает серверный span на каждый запрос, продолжая трассу из
+// входящего traceparent. Span называется по шаблону маршрута, а не по пути,
+// чтобы не раздувать число имен
+func Middleware(skipPaths ...string) echo.MiddlewareFunc {
+	tracer := Tracer()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			for _, p := range skipPaths {
+				if req.URL.Path == p {
+					return next(c)
+				}
+			}
+
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+
+			ctx, span := tracer.Start(ctx, req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", req.Method),
+					attribute.String("http.route", route),
+					attribute.String("url.path", req.URL.Path),
+					attribute.String("client.address", c.RealIP()),
+				),
+			)
+			defer span.End()
+
+			c.SetRequest(req.WithContext(ctx))
+			err := next(c)
+
+			status := httputil.ResponseStatus(c, err)
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, strconv.Itoa(status))
+			}
+			if err != nil {
+				span.RecordError(err)
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryTracer открывает span на каждый запрос pgx и на ожидание соединения из
+// пула. Аргументы запросов в span не попадают
+type QueryTracer struct {
+	tracer trace.Tracer
+}
+
+func NewQueryTracer() *QueryTracer {
+	return &QueryTracer{tracer: Tracer()}
+}
+
+var dbSystem = attribute.String("db.system.name", "postgresql")
+
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = t.tracer.Start(ctx, "postgres "+operation(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbSystem, attribute.String("db.query.text", data.SQL)),
+	)
+	return ctx
+}
+
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	} else {
+		span.SetAttributes(attribute.Int64("db.response.rows_affected", data.CommandTag.RowsAffected()))
+	}
+	span.End()
+}
+
+// TraceAcquireStart показывает, сколько запрос ждал свободное соединение
+func (t *QueryTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	ctx, _ = t.tracer.Start(ctx, "pgxpool acquire", trace.WithAttributes(dbSystem))
+	return ctx
+}
+
+func (t *QueryTracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+	span.End()
+}
+
+// operation возвращает первое слово запроса (SELECT, INSERT, ...), пропуская
+// начальный комментарий /* request_id=... */
+func operation(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if strings.HasPrefix(sql, "/*") {
+		if _, rest, ok := strings.Cut(sql, "*/"); ok {
+			sql = rest
+		}
+	}
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+	return strings.ToUpper(fields[0])
+}
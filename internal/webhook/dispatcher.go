@@ -0,0 +1,247 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/untibullet/subscription-service-em/internal/health"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"github.com/untibullet/subscription-service-em/internal/reqctx"
+	"go.uber.org/zap"
+)
+
+// DispatcherConfig - параметры доставки событий
+type DispatcherConfig struct {
+	PollInterval   time.Duration // как часто проверять очередь
+	BatchSize      int           // сколько доставок брать за раз
+	Concurrency    int           // сколько доставок выполнять параллельно
+	RequestTimeout time.Duration // таймаут HTTP-запроса к получателю
+	MaxAttempts    int           // после стольких неудач доставка уходит в dead letter
+	InitialBackoff time.Duration // задержка перед второй попыткой
+	MaxBackoff     time.Duration // верхняя граница задержки
+}
+
+// Dispatcher доставляет события из очереди webhook_deliveries получателям
+type Dispatcher struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+	cfg    DispatcherConfig
+	beat   *health.Heartbeat
+	log    *zap.Logger
+}
+
+func NewDispatcher(repo repository.WebhookRepository, cfg DispatcherConfig, log *zap.Logger) *Dispatcher {
+	return &Dispatcher{
+		repo:   repo,
+		client: &http.Client{Timeout: cfg.RequestTimeout},
+		cfg:    cfg,
+		beat:   health.NewHeartbeat(health.MissedRuns*cfg.PollInterval + batchLease(cfg)),
+		log:    log,
+	}
+}
+
+// Check сообщает, удается ли забирать доставки из очереди
+func (d *Dispatcher) Check(ctx context.Context) error {
+	return d.beat.Check(ctx)
+}
+
+// Run обрабатывает очередь до отмены ctx
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.log.Info("webhook dispatcher started", zap.Duration("poll_interval", d.cfg.PollInterval))
+
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// пока очередь полная, забираем следующую пачку без ожидания
+		for {
+			if n := d.dispatchBatch(ctx); n < d.cfg.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			d.log.Info("webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// batchLease - на сколько откладываются взятые доставки; с запасом покрывает все попытки пачки
+func batchLease(cfg DispatcherConfig) time.Duration {
+	return cfg.RequestTimeout*time.Duration(cfg.BatchSize/max(cfg.Concurrency, 1)+1) + time.Minute
+}
+
+// dispatchBatch доставляет одну пачку и возвращает ее размер
+func (d *Dispatcher) dispatchBatch(ctx context.Context) int {
+	deliveries, err := d.repo.ClaimDue(ctx, d.cfg.BatchSize, batchLease(d.cfg))
+	if err != nil {
+		if ctx.Err() == nil {
+			d.beat.Fail(err)
+			d.log.Error("claim webhook deliveries failed", zap.Error(err))
+		}
+		return 0
+	}
+	d.beat.Beat()
+	if len(deliveries) == 0 {
+		return 0
+	}
+
+	webhooks := make(map[uuid.UUID]*models.Webhook)
+	sem := make(chan struct{}, max(d.cfg.Concurrency, 1))
+	var wg sync.WaitGroup
+
+	for _, delivery := range deliveries {
+		wh, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			wh, err = d.repo.GetByID(reqctx.WithTenant(ctx, delivery.TenantID), delivery.WebhookID)
+			if err != nil {
+				d.skip(ctx, delivery, err)
+				continue
+			}
+			webhooks[delivery.WebhookID] = wh
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(wh *models.Webhook, delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			d.deliver(ctx, wh, delivery)
+		}(wh, delivery)
+	}
+
+	wg.Wait()
+	return len(deliveries)
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, wh *models.Webhook, delivery *models.WebhookDelivery) {
+	log := d.log.With(
+		zap.String("webhook_id", wh.ID.String()),
+		zap.String("delivery_id", delivery.ID.String()),
+		zap.String("event_type", string(delivery.EventType)),
+		zap.Int("attempt", delivery.Attempts+1),
+	)
+
+	if !wh.Active {
+		// получатель отключен после постановки события в очередь
+		res := models.DeliveryResult{Err: errors.New("webhook is inactive"), Dead: true}
+		if err := d.repo.RecordAttempt(ctx, delivery.ID, res); err != nil {
+			log.Error("record webhook attempt failed", zap.Error(err))
+		}
+		return
+	}
+
+	status, err := d.send(ctx, wh, delivery)
+	if ctx.Err() != nil {
+		// остановка сервиса: доставка вернется в очередь по истечении lease
+		return
+	}
+
+	res := d.result(log, delivery, err)
+	if status != 0 {
+		res.ResponseStatus = &status
+	}
+	if err == nil {
+		log.Debug("webhook delivered", zap.Int("status", status))
+	}
+
+	if err := d.repo.RecordAttempt(ctx, delivery.ID, res); err != nil {
+		log.Error("record webhook attempt failed", zap.Error(err))
+	}
+}
+
+// skip записывает неудачную попытку доставки, получателя которой не удалось
+// прочитать. Без этого взятая доставка оставалась бы отложенной на lease
+// и не попадала в dead letter. Удаленный получатель доставку не примет никогда
+func (d *Dispatcher) skip(ctx context.Context, delivery *models.WebhookDelivery, err error) {
+	log := d.log.With(
+		zap.String("webhook_id", delivery.WebhookID.String()),
+		zap.String("delivery_id", delivery.ID.String()),
+		zap.Int("attempt", delivery.Attempts+1),
+	)
+	err = fmt.Errorf("get webhook: %w", err)
+	res := models.DeliveryResult{Err: err, Dead: true}
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		log.Warn("webhook delivery moved to dead letter", zap.Error(err))
+	} else {
+		res = d.result(log, delivery, err)
+	}
+	if err := d.repo.RecordAttempt(ctx, delivery.ID, res); err != nil {
+		log.Error("record webhook attempt failed", zap.Error(err))
+	}
+}
+
+// result возвращает итог попытки: при ошибке следующая попытка через backoff
+// или dead letter, если попытки исчерпаны
+func (d *Dispatcher) result(log *zap.Logger, delivery *models.WebhookDelivery, err error) models.DeliveryResult {
+	res := models.DeliveryResult{Err: err}
+	if err == nil {
+		return res
+	}
+
+	attempt := delivery.Attempts + 1
+	if attempt >= d.cfg.MaxAttempts {
+		res.Dead = true
+		log.Warn("webhook delivery moved to dead letter", zap.Error(err))
+	} else {
+		res.NextAttemptAt = time.Now().UTC().Add(d.backoff(attempt))
+		log.Info("webhook delivery failed, will retry", zap.Time("next_attempt_at", res.NextAttemptAt), zap.Error(err))
+	}
+	return res
+}
+
+// send выполняет HTTP-запрос и возвращает код ответа (0, если ответа не было)
+func (d *Dispatcher) send(ctx context.Context, wh *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "subscription-service-webhooks/1.0")
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(wh.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// дочитываем тело, чтобы соединение вернулось в пул
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, errors.New("unexpected response status " + resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff возвращает экспоненциальную задержку перед попыткой attempt+1 с джиттером ±20%
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.InitialBackoff
+	for i := 1; i < attempt && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, d.cfg.MaxBackoff)
+
+	jitter := time.Duration(rand.Int64N(int64(delay)/5*2+1)) - delay/5
+	return delay + jitter
+}
@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+	"go.uber.org/zap"
+)
+
+// fakeWebhookRepo - очередь доставок в памяти. Методы, не нужные
+// диспетчеру, не реализованы
+type fakeWebhookRepo struct {
+	repository.WebhookRepository
+
+	webhook    *models.Webhook
+	getErr     error
+	deliveries []*models.WebhookDelivery
+
+	mu      sync.Mutex
+	results map[uuid.UUID]models.DeliveryResult
+}
+
+func (r *fakeWebhookRepo) ClaimDue(context.Context, int, time.Duration) ([]*models.WebhookDelivery, error) {
+	claimed := r.deliveries
+	r.deliveries = nil
+	return claimed, nil
+}
+
+func (r *fakeWebhookRepo) GetByID(context.Context, uuid.UUID) (*models.Webhook, error) {
+	if r.getErr != nil {
+		return nil, r.getErr
+	}
+	return r.webhook, nil
+}
+
+func (r *fakeWebhookRepo) RecordAttempt(_ context.Context, id uuid.UUID, res models.DeliveryResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results[id] = res
+	return nil
+}
+
+func testDispatcherConfig() DispatcherConfig {
+	return DispatcherConfig{
+		PollInterval:   time.Second,
+		BatchSize:      10,
+		Concurrency:    2,
+		RequestTimeout: 5 * time.Second,
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+	}
+}
+
+func TestDispatcherDeliver(t *testing.T) {
+	const secret = "whsec_test"
+	payload := []byte(`{"type":"subscription.created"}`)
+
+	tests := []struct {
+		name     string
+		status   int   // ответ получателя
+		inactive bool  // получатель отключен
+		attempts int   // попыток до этой
+		getErr   error // ошибка чтения получателя
+
+		wantRequest bool
+		wantErr     bool
+		wantDead    bool
+		wantRetry   bool
+	}{
+		{name: "delivered", status: http.StatusNoContent, wantRequest: true},
+		{name: "server error is retried", status: http.StatusInternalServerError, wantRequest: true, wantErr: true, wantRetry: true},
+		{name: "last attempt goes to dead letter", status: http.StatusBadGateway, attempts: 2, wantRequest: true, wantErr: true, wantDead: true},
+		{name: "inactive webhook goes to dead letter", status: http.StatusOK, inactive: true, wantErr: true, wantDead: true},
+		{name: "deleted webhook goes to dead letter", getErr: repository.ErrWebhookNotFound, wantErr: true, wantDead: true},
+		{name: "webhook read error is retried", getErr: errors.New("connection reset"), wantErr: true, wantRetry: true},
+		{name: "webhook read error on last attempt", getErr: errors.New("connection reset"), attempts: 2, wantErr: true, wantDead: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				body, _ := io.ReadAll(r.Body)
+				if !Verify(secret, r.Header.Get(HeaderSignature), r.Header.Get(HeaderTimestamp), body, time.Minute) {
+					t.Error("receiver got invalid signature")
+				}
+				if got := r.Header.Get(HeaderEvent); got != string(models.EventSubscriptionCreated) {
+					t.Errorf("%s = %q", HeaderEvent, got)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+
+			wh := &models.Webhook{ID: uuid.New(), URL: receiver.URL, Secret: secret, Active: !tt.inactive}
+			delivery := &models.WebhookDelivery{
+				ID:        uuid.New(),
+				WebhookID: wh.ID,
+				EventID:   uuid.NewString(),
+				EventType: models.EventSubscriptionCreated,
+				Payload:   payload,
+				Attempts:  tt.attempts,
+			}
+			repo := &fakeWebhookRepo{
+				webhook:    wh,
+				getErr:     tt.getErr,
+				deliveries: []*models.WebhookDelivery{delivery},
+				results:    make(map[uuid.UUID]models.DeliveryResult),
+			}
+
+			d := NewDispatcher(repo, testDispatcherConfig(), zap.NewNop())
+			start := time.Now().UTC()
+			if n := d.dispatchBatch(context.Background()); n != 1 {
+				t.Fatalf("dispatchBatch() = %d, want 1", n)
+			}
+
+			if got := requests.Load() > 0; got != tt.wantRequest {
+				t.Errorf("request sent = %v, want %v", got, tt.wantRequest)
+			}
+			res, ok := repo.results[delivery.ID]
+			if !ok {
+				t.Fatal("attempt was not recorded")
+			}
+			if (res.Err != nil) != tt.wantErr {
+				t.Errorf("Err = %v, want error %v", res.Err, tt.wantErr)
+			}
+			if res.Dead != tt.wantDead {
+				t.Errorf("Dead = %v, want %v", res.Dead, tt.wantDead)
+			}
+			if retry := !res.NextAttemptAt.IsZero(); retry != tt.wantRetry {
+				t.Errorf("NextAttemptAt = %v, want retry %v", res.NextAttemptAt, tt.wantRetry)
+			} else if retry && !res.NextAttemptAt.After(start) {
+				t.Errorf("NextAttemptAt = %v is not in the future", res.NextAttemptAt)
+			}
+			if tt.wantRequest && (res.ResponseStatus == nil || *res.ResponseStatus != tt.status) {
+				t.Errorf("ResponseStatus = %v, want %d", res.ResponseStatus, tt.status)
+			}
+		})
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	d := NewDispatcher(&fakeWebhookRepo{}, testDispatcherConfig(), zap.NewNop())
+
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{attempt: 1, base: time.Second},
+		{attempt: 2, base: 2 * time.Second},
+		{attempt: 4, base: 8 * time.Second},
+		{attempt: 7, base: time.Minute},
+		{attempt: 20, base: time.Minute},
+	}
+
+	for _, tt := range tests {
+		for range 100 {
+			got := d.backoff(tt.attempt)
+			if got < tt.base*8/10 || got > tt.base*12/10 {
+				t.Fatalf("backoff(%d) = %v, want %v ±20%%", tt.attempt, got, tt.base)
+			}
+		}
+	}
+}
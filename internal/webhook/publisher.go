@@ -0,0 +1,24 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/untibullet/subscription-service-em/internal/models"
+	"github.com/untibullet/subscription-service-em/internal/repository"
+)
+
+// Publisher ставит события из outbox в очередь доставки получателям,
+// подписанным на их тип. Повторная публикация того же события не создает
+// повторных доставок
+type Publisher struct {
+	repo repository.WebhookRepository
+}
+
+func NewPublisher(repo repository.WebhookRepository) *Publisher {
+	return &Publisher{repo: repo}
+}
+
+func (p *Publisher) Publish(ctx context.Context, event models.Event) error {
+	_, err := p.repo.Enqueue(ctx, event)
+	return err
+}
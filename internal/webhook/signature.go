@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки запроса доставки
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-Event-ID"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+// Sign возвращает значение заголовка X-Webhook-Signature:
+// HMAC-SHA256 от "<timestamp>.<body>" на секрете получателя.
+// Метка времени входит в подпись, чтобы получатель мог отклонять повторы
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись доставки на стороне получателя. tolerance
+// ограничивает возраст метки времени; 0 отключает проверку возраста
+func Verify(secret, signature, timestamp string, body []byte, tolerance time.Duration) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if tolerance > 0 {
+		age := time.Since(time.Unix(ts, 0))
+		if age > tolerance || age < -tolerance {
+			return false
+		}
+	}
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	expected := Sign(secret, ts, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
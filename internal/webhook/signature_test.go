@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"type":"subscription.created"}`)
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp string
+		body      []byte
+		tolerance time.Duration
+		want      bool
+	}{
+		{
+			name:      "valid",
+			secret:    secret,
+			signature: Sign(secret, now, body),
+			timestamp: strconv.FormatInt(now, 10),
+			body:      body,
+			tolerance: 5 * time.Minute,
+			want:      true,
+		},
+		{
+			name:      "wrong secret",
+			secret:    "other",
+			signature: Sign(secret, now, body),
+			timestamp: strconv.FormatInt(now, 10),
+			body:      body,
+			tolerance: 5 * time.Minute,
+		},
+		{
+			name:      "tampered body",
+			secret:    secret,
+			signature: Sign(secret, now, body),
+			timestamp: strconv.FormatInt(now, 10),
+			body:      []byte(`{"type":"subscription.deleted"}`),
+			tolerance: 5 * time.Minute,
+		},
+		{
+			name:      "timestamp not covered by signature",
+			secret:    secret,
+			signature: Sign(secret, now, body),
+			timestamp: strconv.FormatInt(now-1, 10),
+			body:      body,
+			tolerance: 5 * time.Minute,
+		},
+		{
+			name:      "stale timestamp",
+			secret:    secret,
+			signature: Sign(secret, now-3600, body),
+			timestamp: strconv.FormatInt(now-3600, 10),
+			body:      body,
+			tolerance: 5 * time.Minute,
+		},
+		{
+			name:      "timestamp from the future",
+			secret:    secret,
+			signature: Sign(secret, now+3600, body),
+			timestamp: strconv.FormatInt(now+3600, 10),
+			body:      body,
+			tolerance: 5 * time.Minute,
+		},
+		{
+			name:      "age check disabled",
+			secret:    secret,
+			signature: Sign(secret, now-3600, body),
+			timestamp: strconv.FormatInt(now-3600, 10),
+			body:      body,
+			want:      true,
+		},
+		{
+			name:      "missing prefix",
+			secret:    secret,
+			signature: Sign(secret, now, body)[len(signaturePrefix):],
+			timestamp: strconv.FormatInt(now, 10),
+			body:      body,
+			tolerance: 5 * time.Minute,
+		},
+		{
+			name:      "malformed timestamp",
+			secret:    secret,
+			signature: Sign(secret, now, body),
+			timestamp: "yesterday",
+			body:      body,
+			tolerance: 5 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.signature, tt.timestamp, tt.body, tt.tolerance); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE subscriptions ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_subscriptions_deleted_at ON subscriptions(deleted_at) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM subscriptions WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_subscriptions_deleted_at;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS deleted_at;
+-- +goose StatementEnd
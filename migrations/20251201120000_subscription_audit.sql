@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE subscription_audit (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id UUID NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255),
+    diff JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_subscription_audit_subscription_id ON subscription_audit(subscription_id, created_at);
+CREATE INDEX idx_subscription_audit_actor ON subscription_audit(actor, created_at);
+CREATE INDEX idx_subscription_audit_created_at ON subscription_audit(created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS subscription_audit;
+-- +goose StatementEnd
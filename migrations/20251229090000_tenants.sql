@@ -0,0 +1,99 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE subscriptions ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE subscription_audit ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE webhooks ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE webhook_deliveries ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE outbox ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+-- все выборки API ограничены организацией, поэтому она идет первой в индексах
+DROP INDEX IF EXISTS idx_subscriptions_user_id;
+DROP INDEX IF EXISTS idx_subscriptions_service_name;
+CREATE INDEX idx_subscriptions_tenant_user_id ON subscriptions(tenant_id, user_id);
+CREATE INDEX idx_subscriptions_tenant_service_name ON subscriptions(tenant_id, service_name);
+CREATE INDEX idx_subscription_audit_tenant_created_at ON subscription_audit(tenant_id, created_at);
+CREATE INDEX idx_webhooks_tenant_id ON webhooks(tenant_id);
+CREATE INDEX idx_api_keys_tenant_id ON api_keys(tenant_id);
+
+-- названия ключей и адреса получателей уникальны в пределах организации
+ALTER TABLE api_keys DROP CONSTRAINT uq_api_keys_name;
+ALTER TABLE api_keys ADD CONSTRAINT uq_api_keys_tenant_name UNIQUE (tenant_id, name);
+ALTER TABLE webhooks ADD CONSTRAINT uq_webhooks_tenant_url UNIQUE (tenant_id, url);
+
+-- Row-level security как дополнительная защита. Политика ограничивает строки
+-- организацией из app.tenant_id; если она не задана, строк не видно совсем.
+-- Все организации видны только при app.system = 'true': так работают фоновые
+-- задачи и соединения при выключенном tenancy.row_level_security.
+-- Суперпользователь и роли с BYPASSRLS политики не соблюдают
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON subscriptions
+    USING (current_setting('app.system', true) = 'true' OR tenant_id = current_setting('app.tenant_id', true));
+
+ALTER TABLE subscription_audit ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscription_audit FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON subscription_audit
+    USING (current_setting('app.system', true) = 'true' OR tenant_id = current_setting('app.tenant_id', true));
+
+ALTER TABLE webhooks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhooks FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON webhooks
+    USING (current_setting('app.system', true) = 'true' OR tenant_id = current_setting('app.tenant_id', true));
+
+ALTER TABLE webhook_deliveries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON webhook_deliveries
+    USING (current_setting('app.system', true) = 'true' OR tenant_id = current_setting('app.tenant_id', true));
+
+ALTER TABLE outbox ENABLE ROW LEVEL SECURITY;
+ALTER TABLE outbox FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON outbox
+    USING (current_setting('app.system', true) = 'true' OR tenant_id = current_setting('app.tenant_id', true));
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON api_keys
+    USING (current_setting('app.system', true) = 'true' OR tenant_id = current_setting('app.tenant_id', true));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP POLICY IF EXISTS tenant_isolation ON api_keys;
+DROP POLICY IF EXISTS tenant_isolation ON outbox;
+DROP POLICY IF EXISTS tenant_isolation ON webhook_deliveries;
+DROP POLICY IF EXISTS tenant_isolation ON webhooks;
+DROP POLICY IF EXISTS tenant_isolation ON subscription_audit;
+DROP POLICY IF EXISTS tenant_isolation ON subscriptions;
+ALTER TABLE api_keys NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE api_keys DISABLE ROW LEVEL SECURITY;
+ALTER TABLE outbox NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE outbox DISABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries DISABLE ROW LEVEL SECURITY;
+ALTER TABLE webhooks NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE webhooks DISABLE ROW LEVEL SECURITY;
+ALTER TABLE subscription_audit NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_audit DISABLE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE webhooks DROP CONSTRAINT IF EXISTS uq_webhooks_tenant_url;
+ALTER TABLE api_keys DROP CONSTRAINT IF EXISTS uq_api_keys_tenant_name;
+ALTER TABLE api_keys ADD CONSTRAINT uq_api_keys_name UNIQUE (name);
+
+DROP INDEX IF EXISTS idx_api_keys_tenant_id;
+DROP INDEX IF EXISTS idx_webhooks_tenant_id;
+DROP INDEX IF EXISTS idx_subscription_audit_tenant_created_at;
+DROP INDEX IF EXISTS idx_subscriptions_tenant_service_name;
+DROP INDEX IF EXISTS idx_subscriptions_tenant_user_id;
+CREATE INDEX idx_subscriptions_user_id ON subscriptions(user_id);
+CREATE INDEX idx_subscriptions_service_name ON subscriptions(service_name);
+
+ALTER TABLE api_keys DROP COLUMN tenant_id;
+ALTER TABLE outbox DROP COLUMN tenant_id;
+ALTER TABLE webhook_deliveries DROP COLUMN tenant_id;
+ALTER TABLE webhooks DROP COLUMN tenant_id;
+ALTER TABLE subscription_audit DROP COLUMN tenant_id;
+ALTER TABLE subscriptions DROP COLUMN tenant_id;
+-- +goose StatementEnd
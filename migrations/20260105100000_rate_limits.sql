@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- корзины токенов ограничения частоты запросов, общие для всех экземпляров сервиса.
+-- Потеря таблицы при сбое лишь сбрасывает лимиты, поэтому она не пишется в WAL
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS rate_limit_buckets;
+-- +goose StatementEnd
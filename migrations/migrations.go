@@ -0,0 +1,35 @@
+// Package migrations содержит миграции схемы БД в формате goose
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// FS - файлы миграций, встроенные в бинарник
+//
+//go:embed *.sql
+var FS embed.FS
+
+// Latest возвращает версию последней миграции - ту, до которой должна быть
+// обновлена схема БД
+func Latest() (int64, error) {
+	files, err := fs.Glob(FS, "*.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, name := range files {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration file name %q: %w", name, err)
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}